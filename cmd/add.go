@@ -1,106 +1,203 @@
-package cmd
-
-import (
-	"bufio"
-	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
-
-	"github.com/mini-page/sniprun/internal/security"
-	"github.com/mini-page/sniprun/internal/snip"
-
-	"github.com/spf13/cobra"
-)
-
-func init() {
-	rootCmd.AddCommand(addCmd)
-}
-
-var addCmd = &cobra.Command{
-	Use:   "add [snip-name]",
-	Short: "Create a new local snip",
-	Long:  `Interactively create a new snip and save it to your local repository`,
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		snipName := args[0]
-		reader := bufio.NewReader(os.Stdin)
-
-		_, _, err := snip.FindSnip(GetConfigDir(), snipName)
-		if err == nil {
-			fmt.Printf("Snip '%s' already exists. Overwrite? (yes/no): ", snipName)
-			response, _ := reader.ReadString('\n')
-			response = strings.ToLower(strings.TrimSpace(response))
-			if response != "yes" && response != "y" {
-				fmt.Println("Cancelled")
-				return
-			}
-		}
-
-		fmt.Printf("Creating snip: %s\n\n", snipName)
-
-		fmt.Print("Description: ")
-		description, _ := reader.ReadString('\n')
-		description = strings.TrimSpace(description)
-
-		fmt.Print("Category (optional): ")
-		category, _ := reader.ReadString('\n')
-		category = strings.TrimSpace(category)
-
-		fmt.Print("Command: ")
-		command, _ := reader.ReadString('\n')
-		command = strings.TrimSpace(command)
-
-		fmt.Print("Arguments (comma-separated, e.g., 'branch,message' or leave empty): ")
-		argsInput, _ := reader.ReadString('\n')
-		argsInput = strings.TrimSpace(argsInput)
-
-		var argsList []string
-		if argsInput != "" {
-			for _, part := range strings.Split(argsInput, ",") {
-				argsList = append(argsList, strings.TrimSpace(part))
-			}
-			fmt.Println("\nUse placeholders in command like: {{branch}}, {{message}}")
-		}
-
-		fmt.Println("\nValidating command security...")
-		result, err := security.ValidateCommand(command)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Security check failed: %v\n", err)
-		} else if result.RiskLevel == security.RiskDangerous {
-			fmt.Fprintf(os.Stderr, "❌ Cannot add: This command appears dangerous\n")
-			fmt.Fprintf(os.Stderr, "Reason: %s\n", result.Reason)
-			os.Exit(1)
-		} else if result.RiskLevel == security.RiskWarning {
-			fmt.Printf("⚠️ Warning: %s\n", result.Reason)
-			if !security.PromptUserConfirmation(command, "Add this snip anyway?") {
-				fmt.Println("Cancelled")
-				return
-			}
-		} else {
-			fmt.Println("✓ Command validated")
-		}
-
-		s := &snip.Snip{
-			Name:        snipName,
-			Description: description,
-			Command:     command,
-			Args:        argsList,
-			Category:    category,
-			Trust:       "local",
-		}
-
-		localPath := filepath.Join(GetConfigDir(), "snips", "local", snipName+".yaml")
-		if err := snip.SaveSnip(s, localPath); err != nil {
-			fmt.Fprintf(os.Stderr, "Error saving snip: %v\n", err)
-			os.Exit(1)
-		}
-
-		fmt.Printf("\n✓ Snip '%s' created successfully\n", snipName)
-		fmt.Printf("Run: sniprun %s", snipName)
-		if len(argsList) > 0 {
-			fmt.Printf(" <%s>", strings.Join(argsList, "> <"))
-		}
-		fmt.Println()
-	},
-}
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mini-page/sniprun/internal/prompt"
+	"github.com/mini-page/sniprun/internal/secrets"
+	"github.com/mini-page/sniprun/internal/security"
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+var addToVault bool
+
+func init() {
+	addCmd.Flags().BoolVar(&addToVault, "vault", false, "Save the snip in the encrypted vault instead of snips/local")
+	rootCmd.AddCommand(addCmd)
+}
+
+var addCmd = &cobra.Command{
+	Use:   "add [snip-name]",
+	Short: "Create a new local snip",
+	Long:  `Interactively create a new snip and save it to your local repository`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		snipName := args[0]
+
+		// Fail before asking for everything if the snip cannot be saved
+		if addToVault && !snip.VaultUnlocked(GetConfigDir()) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", snip.ErrVaultLocked)
+			os.Exit(1)
+		}
+
+		// existing is the snip being overwritten, if any
+		var existing string
+		if _, path, err := snip.FindSnip(GetConfigDir(), snipName); err == nil {
+			existing = path
+			overwrite, err := prompt.Confirm(fmt.Sprintf("Snip '%s' already exists. Overwrite?", snipName))
+			if err != nil {
+				exitWithError(err)
+			}
+			if !overwrite {
+				fmt.Println("Cancelled")
+				return
+			}
+		}
+
+		fmt.Printf("Creating snip: %s\n\n", snipName)
+
+		description := askLine("Description: ")
+		category := askLine("Category (optional): ")
+		command := askLine("Command: ")
+		argsInput := askLine("Arguments (comma-separated, e.g., 'branch,message' or leave empty): ")
+
+		var argsList []snip.Arg
+		if argsInput != "" {
+			for _, part := range strings.Split(argsInput, ",") {
+				argsList = append(argsList, snip.Arg{Name: strings.TrimSpace(part)})
+			}
+			fmt.Println("\nUse placeholders in command like: {{branch}}, {{message}}")
+		}
+
+		command, argsList = convertSecrets(command, argsList)
+
+		fmt.Println("\nValidating command security...")
+		result, err := getValidator().Validate(&security.Request{
+			Command:  command,
+			Snip:     snipName,
+			Category: category,
+			Trust:    "local",
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Security check failed: %v\n", err)
+		} else if result.RiskLevel == security.RiskDangerous {
+			fmt.Fprintf(os.Stderr, "❌ Cannot add: This command appears dangerous\n")
+			fmt.Fprintf(os.Stderr, "Reason: %s\n", result.Reason)
+			os.Exit(1)
+		} else if result.RiskLevel == security.RiskWarning {
+			fmt.Printf("⚠️ Warning: %s\n", result.Reason)
+			confirmed, err := security.PromptUserConfirmation(command, "Add this snip anyway?")
+			if err != nil {
+				exitWithError(err)
+			}
+			if !confirmed {
+				fmt.Println("Cancelled")
+				return
+			}
+		} else {
+			fmt.Println("✓ Command validated")
+		}
+
+		s := &snip.Snip{
+			Name:        snipName,
+			Description: description,
+			Command:     command,
+			Args:        argsList,
+			Category:    category,
+			Trust:       "local",
+		}
+
+		if addToVault {
+			err = snip.SaveVaultSnip(GetConfigDir(), s)
+		} else {
+			localPath := filepath.Join(GetConfigDir(), "snips", "local", snipName+".yaml")
+			err = snip.SaveSnip(s, localPath)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving snip: %v\n", err)
+			os.Exit(1)
+		}
+
+		// An overwritten snip in the other store would shadow the new one
+		// or be shadowed by it, so only one copy is kept
+		if existing != "" {
+			if addToVault && !snip.IsVault(GetConfigDir(), existing) && !snip.IsCommunity(GetConfigDir(), existing) {
+				err = os.Remove(existing)
+			} else if !addToVault && snip.IsVault(GetConfigDir(), existing) {
+				err = snip.RemoveVaultSnip(GetConfigDir(), snipName)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to remove the previous copy of '%s': %v\n", snipName, err)
+			}
+		}
+
+		fmt.Printf("\n✓ Snip '%s' created successfully\n", snipName)
+		fmt.Printf("Run: sniprun %s", snipName)
+		if len(argsList) > 0 {
+			fmt.Printf(" <%s>", strings.Join(s.ArgNames(), "> <"))
+		}
+		fmt.Println()
+	},
+}
+
+// convertSecrets offers to replace every likely secret in a command with a
+// secret argument or an environment variable reference, since snips are
+// stored in plain text and may be shared
+func convertSecrets(command string, args []snip.Arg) (string, []snip.Arg) {
+	matches := secrets.Scan(command)
+	if len(matches) == 0 {
+		return command, args
+	}
+
+	fmt.Printf("\n⚠️  The command appears to contain %d secret(s). Snips are saved in plain text.\n", len(matches))
+	for _, m := range matches {
+		// An earlier answer may have replaced the same value already
+		if !strings.Contains(command, m.Value) {
+			continue
+		}
+
+		fmt.Printf("\n  %s: %s\n", m.Kind, m.Masked())
+		choice := askLine("  Replace with a secret (a)rgument, an (e)nvironment variable, or (k)eep it? [a]: ")
+
+		switch strings.ToLower(strings.TrimSpace(choice)) {
+		case "k", "keep":
+			continue
+		case "e", "env":
+			name := promptDefault("  Variable name", m.EnvName)
+			command = strings.ReplaceAll(command, m.Value, "${"+name+"}")
+		default:
+			name := promptDefault("  Argument name", strings.ToLower(m.EnvName))
+			command = strings.ReplaceAll(command, m.Value, "{{"+name+"}}")
+			args = append(args, snip.Arg{Name: name, Secret: true})
+		}
+	}
+
+	fmt.Printf("\nCommand: %s\n", command)
+	return command, args
+}
+
+// warnStoredSecrets lists the likely secrets kept in a snip's plain text
+// fields, reporting whether there were any
+func warnStoredSecrets(s *snip.Snip) bool {
+	found := s.StoredSecrets()
+	if len(found) == 0 {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "⚠️  %s appears to contain %d secret(s):\n", s.Name, len(found))
+	for _, f := range found {
+		fmt.Fprintf(os.Stderr, "  - %s: %s %s\n", f.Field, f.Kind, f.Masked())
+	}
+	return true
+}
+
+// askLine asks for a line of text, exiting when it cannot be asked
+func askLine(label string) string {
+	answer, err := prompt.Line(label)
+	if err != nil {
+		exitWithError(err)
+	}
+	return strings.TrimSpace(answer)
+}
+
+// promptDefault reads a line, returning def for an empty answer
+func promptDefault(label, def string) string {
+	if answer := askLine(fmt.Sprintf("%s [%s]: ", label, def)); answer != "" {
+		return answer
+	}
+	return def
+}
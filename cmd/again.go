@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mini-page/sniprun/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(againCmd)
+}
+
+var againCmd = &cobra.Command{
+	Use:   "again [snip-name]",
+	Short: "Repeat the last executed snip",
+	Long: `Repeat the most recent run from the history with the same arguments.
+
+If a snip name is given, the most recent run of that snip is repeated instead.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := history.Load(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		snipName := ""
+		if len(args) > 0 {
+			snipName = args[0]
+		}
+
+		entry, err := history.Last(entries, snipName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		runSnip(recallInvocation(entry))
+	},
+}
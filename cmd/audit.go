@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/audit"
+	"github.com/mini-page/sniprun/internal/security"
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditFormat  string
+	auditOutput  string
+	auditDir     string
+	auditWorkers int
+	auditRate    float64
+	auditFailOn  string
+)
+
+func init() {
+	auditCmd.Flags().StringVarP(&auditFormat, "format", "f", "text", "Report format: text, json or sarif")
+	auditCmd.Flags().StringVarP(&auditOutput, "output", "o", "", "Write the report to a file instead of stdout")
+	auditCmd.Flags().StringVar(&auditDir, "dir", "", "Audit snip files under a directory (e.g. a snips repository) instead of installed snips")
+	auditCmd.Flags().IntVarP(&auditWorkers, "workers", "w", 4, "Number of snips checked at the same time")
+	auditCmd.Flags().Float64Var(&auditRate, "rate", 2, "Maximum checks per second when a remote validator is used, 0 for no limit")
+	auditCmd.Flags().StringVar(&auditFailOn, "fail-on", "dangerous", "Exit with status 1 if a snip reaches this risk level (warning, dangerous or none)")
+	rootCmd.AddCommand(auditCmd)
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check all snips with the security validators",
+	Long: `Run the security policy and validators over every installed snip and report
+the results grouped by risk level. Arguments are filled in with their names.
+
+The exit status is 1 if a snip reaches the --fail-on level or cannot be
+checked, so the audit can gate snip repositories in CI:
+
+  sniprun audit --dir ./snips --format sarif --output audit.sarif`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var failLevel security.RiskLevel
+		switch auditFailOn {
+		case "warning", "dangerous":
+			failLevel = security.RiskLevel(auditFailOn)
+		case "none":
+		default:
+			fmt.Fprintf(os.Stderr, "Error: --fail-on must be warning, dangerous or none\n")
+			os.Exit(1)
+		}
+		if auditFormat != "text" && auditFormat != "json" && auditFormat != "sarif" {
+			fmt.Fprintf(os.Stderr, "Error: --format must be text, json or sarif\n")
+			os.Exit(1)
+		}
+
+		var items []*audit.Item
+		var err error
+		if auditDir != "" {
+			items, err = auditItemsFromDir(auditDir)
+		} else {
+			items, err = auditItemsInstalled()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(items) == 0 {
+			fmt.Fprintln(os.Stderr, "No snips found")
+			return
+		}
+
+		// Build both before the workers start rather than racing to do it
+		getPolicy()
+		check := func(s *snip.Snip, command string) (*security.ValidationResult, error) {
+			return validateCommand(s, command, nil)
+		}
+		opts := audit.Options{Workers: auditWorkers, Check: check}
+		if security.IsRemote(getValidator()) && auditRate > 0 {
+			opts.Interval = time.Duration(float64(time.Second) / auditRate)
+		}
+
+		if info, err := os.Stderr.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			opts.Progress = func(done, total int) {
+				fmt.Fprintf(os.Stderr, "\r  %d/%d snips checked", done, total)
+				if done == total {
+					fmt.Fprint(os.Stderr, "\r\033[K")
+				}
+			}
+		}
+
+		entries := audit.Run(items, opts)
+
+		var out io.Writer = os.Stdout
+		if auditOutput != "" {
+			f, err := os.Create(auditOutput)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		switch auditFormat {
+		case "text":
+			audit.WriteText(out, entries)
+		case "json":
+			err = audit.WriteJSON(out, entries)
+		case "sarif":
+			baseDir, _ := os.Getwd()
+			err = audit.WriteSARIF(out, entries, baseDir)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if failLevel != "" && audit.Failed(entries, failLevel) {
+			if auditOutput != "" {
+				fmt.Fprintf(os.Stderr, "Audit failed, see %s\n", auditOutput)
+			}
+			// Deferred calls do not run on os.Exit
+			if f, ok := out.(*os.File); ok && f != os.Stdout {
+				f.Close()
+			}
+			os.Exit(1)
+		}
+	},
+}
+
+// auditItemsInstalled returns every snip listed by 'sniprun list'
+func auditItemsInstalled() ([]*audit.Item, error) {
+	snips, err := snip.ListSnips(GetConfigDir())
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*audit.Item
+	for _, name := range snip.SortNames(snips, nil) {
+		_, path, _ := snip.FindSnip(GetConfigDir(), name)
+		s := snips[name]
+		items = append(items, &audit.Item{Snip: s, Path: path, Command: exampleCommand(s)})
+	}
+	return items, nil
+}
+
+// auditItemsFromDir loads all snip files below a directory
+func auditItemsFromDir(dir string) ([]*audit.Item, error) {
+	var items []*audit.Item
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".yaml") {
+			return nil
+		}
+
+		s, err := snip.LoadSnip(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to load %s: %v\n", path, err)
+			return nil
+		}
+		// Snips of a repository are installed as community snips
+		s.SetTrust("community")
+		items = append(items, &audit.Item{Snip: s, Path: path, Command: exampleCommand(s)})
+		return nil
+	})
+	return items, err
+}
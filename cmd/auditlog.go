@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mini-page/sniprun/internal/auditlog"
+	"github.com/mini-page/sniprun/internal/security"
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditLogLimit  int
+	auditLogOutput string
+)
+
+func init() {
+	auditLogShowCmd.Flags().IntVarP(&auditLogLimit, "limit", "n", 20, "Number of records to show, 0 for all")
+	auditLogExportCmd.Flags().StringVarP(&auditLogOutput, "output", "o", "", "Write to a file instead of stdout")
+
+	auditLogCmd.AddCommand(auditLogShowCmd, auditLogVerifyCmd, auditLogExportCmd)
+	rootCmd.AddCommand(auditLogCmd)
+}
+
+var auditLogCmd = &cobra.Command{
+	Use:   "audit-log",
+	Short: "Inspect the tamper-evident log of executions and approvals",
+	Long: `Every run, blocked command, confirmation and trusted key change is appended
+to an audit log in the config directory. Each record holds the hash of the
+one before it, so editing or deleting records is detected by 'verify'.`,
+}
+
+var auditLogShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the most recent audit records",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		records, err := auditlog.Load(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(records) == 0 {
+			fmt.Println("The audit log is empty")
+			return
+		}
+		if auditLogLimit > 0 && len(records) > auditLogLimit {
+			records = records[len(records)-auditLogLimit:]
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SEQ\tTIME\tUSER\tEVENT\tSNIP\tRISK\tDETAIL")
+		for _, r := range records {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Seq, r.Time.Local().Format("2006-01-02 15:04:05"),
+				r.User, r.Event, orDash(r.Snip), orDash(string(r.RiskLevel)), auditDetail(r))
+		}
+		w.Flush()
+	},
+}
+
+var auditLogVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that no audit record was modified or removed",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		count, err := auditlog.Verify(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			fmt.Fprintf(os.Stderr, "The first %d record(s) are intact\n", count)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Audit log intact: %d record(s)\n", count)
+	},
+}
+
+var auditLogExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the audit log as JSON lines",
+	Long: `Export all audit records as JSON lines, one record per line, including the
+hashes that chain them together.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := auditlog.Verify(GetConfigDir()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+
+		records, err := auditlog.Load(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var out io.Writer = os.Stdout
+		if auditLogOutput != "" {
+			f, err := os.Create(auditLogOutput)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		enc := json.NewEncoder(out)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+// auditRecord describes an event concerning a command of a snip, given with
+// its secrets masked. result may be nil when the command was not checked.
+func auditRecord(event string, s *snip.Snip, shown string, result *security.ValidationResult) *auditlog.Record {
+	r := &auditlog.Record{
+		Event:       event,
+		Snip:        s.Name,
+		Trust:       s.Trust,
+		Profile:     s.Profile,
+		CommandHash: auditlog.HashCommand(shown),
+	}
+	if result != nil {
+		r.RiskLevel = result.RiskLevel
+		r.Verdicts = result.Contributions()
+	}
+	return r
+}
+
+// recordAudit appends a record to the audit log, warning if it cannot
+func recordAudit(r *auditlog.Record, warnings io.Writer) {
+	if err := auditlog.Append(GetConfigDir(), r); err != nil {
+		fmt.Fprintf(warnings, "Warning: failed to record audit log: %v\n", err)
+	}
+}
+
+// requireAudit appends a record to the audit log and exits if it cannot.
+// Runs and approvals must not go unrecorded.
+func requireAudit(r *auditlog.Record) {
+	if err := auditlog.Append(GetConfigDir(), r); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to record audit log: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// checkAudit exits before a run whose record could not be written after it
+func checkAudit() {
+	if err := auditlog.Check(GetConfigDir()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: the run would not be recorded: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// auditDetail summarises the parts of a record that do not have a column
+func auditDetail(r *auditlog.Record) string {
+	var parts []string
+	if r.ExitCode != nil {
+		parts = append(parts, fmt.Sprintf("exit %d", *r.ExitCode))
+	}
+	if len(r.Overrides) > 0 {
+		parts = append(parts, "overrides: "+strings.Join(r.Overrides, ", "))
+	}
+	if r.Profile != "" {
+		parts = append(parts, "profile "+r.Profile)
+	}
+	if r.Schedule != "" {
+		parts = append(parts, "schedule "+r.Schedule)
+	}
+	if r.Detail != "" {
+		parts = append(parts, r.Detail)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/auditlog"
+	"github.com/mini-page/sniprun/internal/bench"
+	"github.com/mini-page/sniprun/internal/sandbox"
+	"github.com/mini-page/sniprun/internal/security"
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchRuns           int
+	benchWarmup         int
+	benchVs             []string
+	benchIgnoreFailure  bool
+	benchSkipCheck      bool
+	benchExportJSON     string
+	benchExportMarkdown string
+)
+
+func init() {
+	benchCmd.Flags().IntVarP(&benchRuns, "runs", "r", 20, "Number of timed runs")
+	benchCmd.Flags().IntVarP(&benchWarmup, "warmup", "w", 3, "Number of untimed runs before measuring")
+	benchCmd.Flags().StringArrayVar(&benchVs, "vs", nil, "Compare against another snip or argument set, e.g. --vs \"my-snip other-arg\" (repeatable)")
+	benchCmd.Flags().BoolVarP(&benchIgnoreFailure, "ignore-failure", "i", false, "Keep going when a run exits with an error")
+	benchCmd.Flags().BoolVar(&benchSkipCheck, "skip-check", false, "Skip security validation")
+	benchCmd.Flags().StringVar(&benchExportJSON, "export-json", "", "Write results with all timings to a JSON file")
+	benchCmd.Flags().StringVar(&benchExportMarkdown, "export-markdown", "", "Write a Markdown summary table to a file")
+	rootCmd.AddCommand(benchCmd)
+}
+
+// benchTarget is one snip invocation to benchmark
+type benchTarget struct {
+	name    string
+	snip    *snip.Snip
+	command string
+	shown   string // command with secrets masked
+	env     []string
+	sandbox *sandbox.Config // set when the policy requires it
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench [snip-name] [args...]",
+	Short: "Benchmark a snip",
+	Long: `Run a snip repeatedly and report mean, median, standard deviation, range and
+outliers of its run time. Output of the snip is discarded.
+
+Use --vs to compare with other snips or with the same snip and different
+arguments:
+
+  sniprun bench build-debug --vs build-release
+  sniprun bench compress fast --vs "compress best" --export-markdown results.md
+
+Times in the JSON export are in nanoseconds.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeSnipArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		invocations := [][]string{args}
+		for _, vs := range benchVs {
+			fields := strings.Fields(vs)
+			if len(fields) == 0 {
+				fmt.Fprintf(os.Stderr, "Error: --vs needs a snip name\n")
+				os.Exit(1)
+			}
+			invocations = append(invocations, fields)
+		}
+
+		var targets []*benchTarget
+		for _, inv := range invocations {
+			s, path, err := snip.FindSnip(GetConfigDir(), inv[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := applyProfile(s, profileName); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			values, err := resolveValues(s, inv[1:], true)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", inv[0], err)
+				os.Exit(1)
+			}
+			command, err := s.InterpolateArgs(values.Args)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", inv[0], err)
+				os.Exit(1)
+			}
+			shown := values.shown(s)
+
+			var result *security.ValidationResult
+			record := auditRecord(auditlog.EventBench, s, shown, nil)
+			if benchSkipCheck {
+				checkSkip(s, values)
+				record.Overrides = []string{"skip-check"}
+			} else {
+				result = checkCommand(s, values)
+			}
+			checkPin(s, path)
+
+			if result != nil {
+				record.RiskLevel = result.RiskLevel
+				record.Verdicts = result.Contributions()
+			}
+			record.Detail = fmt.Sprintf("%d runs, %d warmup", benchRuns, benchWarmup)
+			requireAudit(record)
+
+			sb, err := sandboxFor(s, "", false)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			targets = append(targets, &benchTarget{
+				name:    benchName(s, values.Args),
+				snip:    s,
+				command: command,
+				shown:   shown,
+				env:     values.Env,
+				sandbox: sb,
+			})
+		}
+
+		opts := bench.Options{
+			Runs:          benchRuns,
+			Warmup:        benchWarmup,
+			IgnoreFailure: benchIgnoreFailure,
+		}
+
+		// Only draw a progress counter when someone is watching
+		if info, err := os.Stderr.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			opts.Progress = func(done, total int) {
+				fmt.Fprintf(os.Stderr, "\r  %d/%d runs", done, total)
+				if done == total {
+					fmt.Fprint(os.Stderr, "\r\033[K")
+				}
+			}
+		}
+
+		var results []*bench.Result
+		for i, t := range targets {
+			fmt.Printf("Benchmark %d: %s\n", i+1, t.name)
+
+			opts.Sandbox = t.sandbox
+			opts.Env = t.env
+			result, err := bench.Run(t.snip, t.name, t.command, t.shown, opts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+				os.Exit(1)
+			}
+			results = append(results, result)
+
+			st := result.Stats
+			fmt.Printf("  Time (mean ± σ):   %10s ± %s\n", formatBenchDuration(st.Mean), formatBenchDuration(st.StdDev))
+			fmt.Printf("  Time (median):     %10s\n", formatBenchDuration(st.Median))
+			fmt.Printf("  Range (min … max): %10s … %s    %d runs\n", formatBenchDuration(st.Min), formatBenchDuration(st.Max), len(result.Times))
+			if st.Outliers > 0 {
+				fmt.Printf("  Warning: %d statistical outlier(s) detected, consider more --warmup runs or a quieter system\n", st.Outliers)
+			}
+			if result.Failures > 0 {
+				fmt.Printf("  Warning: %d run(s) exited with an error\n", result.Failures)
+			}
+			fmt.Println()
+		}
+
+		if len(results) > 1 {
+			fastest := bench.Fastest(results)
+			fmt.Println("Summary")
+			fmt.Printf("  %s ran\n", fastest.Name)
+			for _, r := range results {
+				if r == fastest {
+					continue
+				}
+				ratio, dev := bench.Ratio(fastest.Stats, r.Stats)
+				fmt.Printf("    %.2f ± %.2f times faster than %s\n", ratio, dev, r.Name)
+			}
+		}
+
+		if benchExportJSON != "" {
+			if err := bench.ExportJSON(results, benchExportJSON); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if benchExportMarkdown != "" {
+			if err := bench.ExportMarkdown(results, benchExportMarkdown); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+// benchName labels a benchmark with the snip and its command line
+// arguments, hiding secret values
+func benchName(s *snip.Snip, args []string) string {
+	parts := []string{s.Name}
+	masked := s.MaskArgs(args)
+	for _, i := range s.Positions() {
+		parts = append(parts, masked[i])
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatBenchDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return fmt.Sprintf("%.3f s", d.Seconds())
+	case d >= time.Millisecond:
+		return fmt.Sprintf("%.1f ms", float64(d)/float64(time.Millisecond))
+	default:
+		return fmt.Sprintf("%.1f µs", float64(d)/float64(time.Microsecond))
+	}
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/auditlog"
+	"github.com/mini-page/sniprun/internal/history"
+	"github.com/mini-page/sniprun/internal/pin"
+	"github.com/mini-page/sniprun/internal/runlog"
+	"github.com/mini-page/sniprun/internal/schedule"
+	"github.com/mini-page/sniprun/internal/secrets"
+	"github.com/mini-page/sniprun/internal/security"
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+}
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run scheduled snips in the foreground",
+	Long: `Run in the foreground and execute scheduled snips when their cron expression matches.
+
+Schedules are re-read every minute, so 'sniprun schedule add' and 'remove' take
+effect without restarting the daemon. A job is skipped while its previous run
+is still going. Output of each run is appended to the job
+log ('sniprun schedule logs <id>') and captured with timestamps ('sniprun logs').`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := os.MkdirAll(schedule.LogDir(GetConfigDir()), 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating log directory: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Build the validator and policy up front rather than racing to do it
+		// in each job
+		getValidator()
+		getPolicy()
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+		fmt.Printf("sniprun daemon started (config: %s)\n", GetConfigDir())
+
+		for {
+			now := time.Now()
+			next := now.Truncate(time.Minute).Add(time.Minute)
+
+			select {
+			case <-stop:
+				fmt.Println("sniprun daemon stopped")
+				return
+			case tick := <-time.After(next.Sub(now)):
+				runDueJobs(tick.Truncate(time.Minute))
+			}
+		}
+	},
+}
+
+// running holds the IDs of the jobs that have not finished yet
+var running = struct {
+	sync.Mutex
+	ids map[string]bool
+}{ids: make(map[string]bool)}
+
+// runDueJobs starts every job whose cron expression matches the given minute,
+// skipping jobs whose previous run is still going
+func runDueJobs(minute time.Time) {
+	jobs, err := schedule.LoadJobs(GetConfigDir())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[%s] Error: %v\n", minute.Format(time.RFC3339), err)
+		return
+	}
+
+	for _, job := range jobs {
+		cron, err := schedule.ParseCron(job.Cron)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[%s] Skipping %s: %v\n", minute.Format(time.RFC3339), job.ID, err)
+			continue
+		}
+		if !cron.Matches(minute) {
+			continue
+		}
+
+		running.Lock()
+		busy := running.ids[job.ID]
+		running.ids[job.ID] = true
+		running.Unlock()
+		if busy {
+			fmt.Printf("[%s] Skipping %s (%s): the previous run is still going\n", minute.Format(time.RFC3339), job.Snip, job.ID)
+			continue
+		}
+
+		go func(job *schedule.Job) {
+			defer func() {
+				running.Lock()
+				delete(running.ids, job.ID)
+				running.Unlock()
+			}()
+			runJob(job)
+		}(job)
+	}
+}
+
+// runJob executes one scheduled snip and appends its output to the job log.
+// The log is rotated at log_max_bytes and each run adds at most that much.
+func runJob(job *schedule.Job) {
+	started := time.Now()
+	fmt.Printf("[%s] Running %s (%s)\n", started.Format(time.RFC3339), job.Snip, job.ID)
+
+	logFile, err := runlog.OpenAppend(schedule.LogPath(GetConfigDir(), job.ID), GetConfig().LogMaxBytes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[%s] Error opening log for %s: %v\n", started.Format(time.RFC3339), job.ID, err)
+		return
+	}
+	defer logFile.Close()
+
+	fmt.Fprintf(logFile, "=== %s run of %s ===\n", started.Format(time.RFC3339), job.Snip)
+
+	status, err := executeJob(job, logFile)
+	if err != nil {
+		fmt.Fprintf(logFile, "=== %s: %v ===\n\n", status, err)
+		fmt.Printf("[%s] %s (%s) %s: %v\n", time.Now().Format(time.RFC3339), job.Snip, job.ID, status, err)
+		return
+	}
+
+	fmt.Fprintf(logFile, "=== %s after %s ===\n\n", status, time.Since(started).Round(time.Millisecond))
+	fmt.Printf("[%s] %s (%s) %s\n", time.Now().Format(time.RFC3339), job.Snip, job.ID, status)
+}
+
+// executeJob validates and runs a job, returning a short status for the log
+func executeJob(job *schedule.Job, logFile *os.File) (string, error) {
+	s, path, err := snip.FindSnip(GetConfigDir(), job.Snip)
+	if err != nil {
+		return "skipped", err
+	}
+	if err := applyProfile(s, job.Profile); err != nil {
+		return "skipped", err
+	}
+
+	// Changed community snips need approval, which only an interactive run
+	// can give
+	if snip.IsCommunity(GetConfigDir(), path) {
+		pins, err := pin.Load(GetConfigDir())
+		if err != nil {
+			return "skipped", err
+		}
+		if p := pins[s.Name]; p != nil && !p.Matches(s.Command) {
+			return "skipped", fmt.Errorf("community snip changed since it was approved; run 'sniprun run %s' to review it", s.Name)
+		}
+	}
+
+	values, err := resolveValues(s, job.Args, false)
+	if err != nil {
+		return "skipped", err
+	}
+	command, err := s.InterpolateArgs(values.Args)
+	if err != nil {
+		return "skipped", err
+	}
+	shown := values.shown(s)
+
+	// Nobody can answer a prompt here, so risky commands only run if the
+	// exact command was approved when it was scheduled
+	result, err := validateCommand(s, command, values)
+	if err != nil {
+		fmt.Fprintf(logFile, "Warning: Security check failed: %v\n", err)
+	} else if result.RiskLevel == security.RiskDangerous {
+		record := auditRecord(auditlog.EventBlocked, s, shown, result)
+		record.Schedule = job.ID
+		recordAudit(record, logFile)
+		return "blocked", fmt.Errorf("command appears dangerous: %s", result.Reason)
+	} else if needsConfirmation(s, result.RiskLevel) && (!job.Approved || shown != job.Command) {
+		return "skipped", fmt.Errorf("command needs approval (%s); schedule it again with 'sniprun schedule add'", confirmationReason(s, result))
+	}
+	if alwaysConfirm(s) && (!job.Approved || shown != job.Command) {
+		return "skipped", fmt.Errorf("command needs approval (%s); schedule it again with 'sniprun schedule add'", confirmationReason(s, nil))
+	}
+
+	fmt.Fprintf(logFile, "$ %s\n", shown)
+
+	// Scheduled runs start from the home directory rather than wherever the
+	// daemon happened to be launched
+	home, _ := os.UserHomeDir()
+	output := secrets.NewRedactor(runlog.Limit(logFile, GetConfig().LogMaxBytes), values.Secrets)
+	opts := snip.ExecOptions{Dir: home, Stdout: output, Stderr: output, Env: values.Env}
+	opts.Sandbox, err = sandboxFor(s, home, false)
+	if err != nil {
+		return "skipped", err
+	}
+
+	if err := auditlog.Check(GetConfigDir()); err != nil {
+		return "skipped", fmt.Errorf("the run would not be recorded: %w", err)
+	}
+
+	// Output of scheduled runs is always captured with per-line timestamps
+	runLog := startCapture(s, values, &opts, logFile)
+	if runLog != nil {
+		runLog.Header("scheduled job %s", job.ID)
+	}
+
+	started := time.Now()
+	err = s.Run(command, opts)
+	output.Flush()
+
+	entry := history.NewEntry(s, values.Args, home, started, err)
+	entry.Schedule = job.ID
+	finishRun(entry, runLog, logFile)
+	if entry.ID > 0 {
+		fmt.Fprintf(logFile, "Recorded as run #%d, see 'sniprun logs %d'\n", entry.ID, entry.ID)
+	}
+
+	record := auditRecord(auditlog.EventRun, s, shown, result)
+	record.Schedule = job.ID
+	record.ExitCode = &entry.ExitCode
+	if auditErr := auditlog.Append(GetConfigDir(), record); auditErr != nil {
+		return "failed", fmt.Errorf("failed to record audit log: %w", auditErr)
+	}
+
+	if err != nil {
+		return "failed", err
+	}
+	return "exit 0", nil
+}
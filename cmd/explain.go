@@ -1,55 +1,152 @@
-package cmd
-
-import (
-	"fmt"
-	"os"
-	"strings"
-
-	"github.com/mini-page/sniprun/internal/snip"
-
-	"github.com/spf13/cobra"
-)
-
-func init() {
-	rootCmd.AddCommand(explainCmd)
-}
-
-var explainCmd = &cobra.Command{
-	Use:   "explain [snip-name]",
-	Short: "Show what a snip will execute",
-	Long:  `Display detailed information about a snip without executing it`,
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		snipName := args[0]
-
-		s, path, err := snip.FindSnip(GetConfigDir(), snipName)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
-
-		fmt.Printf("Snip: %s\n", s.Name)
-		fmt.Printf("Description: %s\n", s.Description)
-		fmt.Printf("Category: %s\n", s.Category)
-		fmt.Printf("Trust: %s\n", s.Trust)
-		fmt.Printf("Path: %s\n\n", path)
-
-		fmt.Println("Command:")
-		fmt.Printf("  %s\n\n", s.Command)
-
-		if len(s.Args) > 0 {
-			fmt.Printf("Arguments required: %s\n", strings.Join(s.Args, ", "))
-			fmt.Printf("Usage: sniprun %s <%s>\n", s.Name, strings.Join(s.Args, "> <"))
-
-			fmt.Println("\nExample with placeholders:")
-			exampleArgs := make([]string, len(s.Args))
-			for i, arg := range s.Args {
-				exampleArgs[i] = fmt.Sprintf("<your-%s>", arg)
-			}
-			command, _ := s.InterpolateArgs(exampleArgs)
-			fmt.Printf("  %s\n", command)
-		} else {
-			fmt.Printf("Usage: sniprun %s\n", s.Name)
-		}
-	},
-}
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain [snip-name]",
+	Short: "Show what a snip will execute",
+	Long:  `Display detailed information about a snip without executing it`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		snipName := args[0]
+
+		s, path, err := snip.FindSnip(GetConfigDir(), snipName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		profileErr := applyProfile(s, profileName)
+
+		fmt.Printf("Snip: %s\n", s.Name)
+		fmt.Printf("Description: %s\n", s.Description)
+		fmt.Printf("Category: %s\n", s.Category)
+		fmt.Printf("Trust: %s\n", s.Trust)
+		if s.DeclaredTrust != "" {
+			fmt.Printf("  ⚠️  The file claims trust '%s', which is ignored\n", s.DeclaredTrust)
+		}
+		if s.Capabilities != nil {
+			fmt.Printf("Capabilities: %s\n", s.Capabilities.Summary())
+		} else {
+			fmt.Println("Capabilities: not declared")
+		}
+		switch {
+		case s.Destructive:
+			fmt.Printf("Confirmation: destructive, type '%s' to run\n", s.TypedConfirmation(s.MaskArgs(exampleArgs(s))))
+		case alwaysConfirm(s):
+			fmt.Println("Confirmation: asked before every run")
+		}
+		if s.Prompt != "" {
+			fmt.Printf("Prompt: %s\n", s.Prompt)
+		}
+		fmt.Printf("Path: %s\n", path)
+		if s.Profile != "" {
+			fmt.Printf("Profile: %s\n", s.Profile)
+		}
+		if profileErr != nil {
+			fmt.Printf("  ⚠️  %v\n", profileErr)
+		}
+		fmt.Println()
+
+		fmt.Println("Command:")
+		fmt.Printf("  %s\n\n", s.Command)
+		if vars := s.InterpolateVars(s.Command); vars != s.Command {
+			fmt.Printf("With the %s profile:\n", s.Profile)
+			fmt.Printf("  %s\n\n", vars)
+		}
+
+		if len(s.Env) > 0 {
+			fmt.Println("Environment:")
+			for _, e := range s.Env {
+				fmt.Printf("  %s: %s\n", e.Name, e.Display())
+			}
+			fmt.Println()
+		}
+
+		if len(s.Args) > 0 {
+			fmt.Printf("Arguments required: %s\n", strings.Join(argDescriptions(s), ", "))
+			if names := s.UsageNames(); len(names) > 0 {
+				fmt.Printf("Usage: sniprun %s <%s>\n", s.Name, strings.Join(names, "> <"))
+			} else {
+				fmt.Printf("Usage: sniprun %s\n", s.Name)
+			}
+
+			fmt.Println("\nExample with placeholders:")
+			command, _ := s.InterpolateArgs(exampleArgs(s))
+			fmt.Printf("  %s\n", command)
+		} else {
+			fmt.Printf("Usage: sniprun %s\n", s.Name)
+		}
+
+		printSecurityCheck(s)
+	},
+}
+
+// exampleArgs are <your-arg> placeholders for the snip's arguments
+func exampleArgs(s *snip.Snip) []string {
+	args := make([]string, len(s.Args))
+	for i, arg := range s.Args {
+		args[i] = fmt.Sprintf("<your-%s>", arg.Name)
+	}
+	return args
+}
+
+// argDescriptions names the snip's arguments, noting which are secret and
+// where values not given on the command line come from
+func argDescriptions(s *snip.Snip) []string {
+	var descriptions []string
+	for _, arg := range s.Args {
+		var notes []string
+		if arg.Secret {
+			notes = append(notes, "secret")
+		}
+		if arg.From != "" {
+			notes = append(notes, "from "+arg.From)
+		}
+
+		if len(notes) > 0 {
+			descriptions = append(descriptions, fmt.Sprintf("%s (%s)", arg.Name, strings.Join(notes, ", ")))
+		} else {
+			descriptions = append(descriptions, arg.Name)
+		}
+	}
+	return descriptions
+}
+
+// printSecurityCheck shows every validator's verdict on a snip, using
+// placeholder words for its arguments
+func printSecurityCheck(s *snip.Snip) {
+	fmt.Println("\nSecurity check:")
+
+	result, err := validateCommand(s, exampleCommand(s), nil)
+	if err != nil {
+		fmt.Printf("  Failed: %v\n", err)
+		return
+	}
+
+	fmt.Printf("  Risk: %s\n", result.RiskLevel)
+	for _, line := range result.Explanation() {
+		fmt.Printf("  - %s\n", line)
+	}
+}
+
+// exampleCommand interpolates a snip with its argument names, which keeps
+// the command parseable unlike the <your-arg> placeholders shown to users
+func exampleCommand(s *snip.Snip) string {
+	command, err := s.InterpolateArgs(s.ArgNames())
+	if err != nil {
+		return s.Command
+	}
+	return command
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	historySnip   string
+	historyFailed bool
+	historyHere   bool
+	historySince  time.Duration
+	historyLimit  int
+)
+
+func init() {
+	historyCmd.Flags().StringVarP(&historySnip, "snip", "s", "", "Only show runs of this snip")
+	historyCmd.Flags().BoolVar(&historyFailed, "failed", false, "Only show runs that exited with an error")
+	historyCmd.Flags().BoolVar(&historyHere, "here", false, "Only show runs started in the current directory")
+	historyCmd.Flags().DurationVar(&historySince, "since", 0, "Only show runs within this duration, e.g. 24h")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Maximum number of runs to show (0 for all)")
+	rootCmd.AddCommand(historyCmd)
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show previously executed snips",
+	Long: `Show snips executed with 'sniprun run', most recent last.
+
+Repeat a run with 'sniprun run !<id>' or 'sniprun again'.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := history.Load(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		cwd, _ := os.Getwd()
+
+		var matched []*history.Entry
+		for _, e := range entries {
+			if historySnip != "" && e.Snip != historySnip {
+				continue
+			}
+			if historyFailed && e.ExitCode == 0 {
+				continue
+			}
+			if historyHere && e.Cwd != cwd {
+				continue
+			}
+			if historySince > 0 && time.Since(e.Timestamp) > historySince {
+				continue
+			}
+			matched = append(matched, e)
+		}
+
+		if len(matched) == 0 {
+			fmt.Println("No matching runs in history")
+			return
+		}
+
+		if historyLimit > 0 && len(matched) > historyLimit {
+			matched = matched[len(matched)-historyLimit:]
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tWHEN\tEXIT\tDURATION\tCOMMAND\tDIRECTORY")
+		for _, e := range matched {
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\n",
+				e.ID,
+				e.Timestamp.Local().Format("2006-01-02 15:04:05"),
+				e.ExitCode,
+				e.Duration.Round(time.Millisecond),
+				formatInvocation(e),
+				e.Cwd,
+			)
+		}
+		w.Flush()
+	},
+}
+
+// formatInvocation renders an entry as it was typed, hiding secret values
+func formatInvocation(e *history.Entry) string {
+	parts := []string{e.Snip}
+	for i, arg := range e.Args {
+		secret := false
+		for _, p := range e.SecretArgs {
+			if p == i {
+				secret = true
+			}
+		}
+
+		switch {
+		case secret:
+			parts = append(parts, "****")
+		case arg == "" || strings.ContainsAny(arg, " \t\"'"):
+			parts = append(parts, fmt.Sprintf("%q", arg))
+		default:
+			parts = append(parts, arg)
+		}
+	}
+	return strings.Join(parts, " ")
+}
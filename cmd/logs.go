@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/history"
+	"github.com/mini-page/sniprun/internal/runlog"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsFollow bool
+	logsGrep   string
+	logsTail   int
+	logsStream string
+)
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new output until the run finishes")
+	logsCmd.Flags().StringVarP(&logsGrep, "grep", "g", "", "Only show lines matching this regular expression")
+	logsCmd.Flags().IntVarP(&logsTail, "tail", "n", 0, "Only show the last N lines")
+	logsCmd.Flags().StringVar(&logsStream, "stream", "", "Only show one stream: out or err")
+	rootCmd.AddCommand(logsCmd)
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [run-id|snip-name]",
+	Short: "Show captured output of past runs",
+	Long: `Show the output captured for a run, identified by its history ID or by snip
+name for the most recent captured run of that snip. Without an argument the
+runs that have captured output are listed.
+
+Output is captured for scheduled runs, and for 'sniprun run' with --capture or
+capture_output: true in the config. Every line is prefixed with its timestamp
+and stream.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := history.Load(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(args) == 0 {
+			listCapturedRuns(entries)
+			return
+		}
+
+		logName, err := findCapturedRun(entries, args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var pattern *regexp.Regexp
+		if logsGrep != "" {
+			if pattern, err = regexp.Compile(logsGrep); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --grep pattern: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		path := filepath.Join(runlog.Dir(GetConfigDir()), logName)
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error: the log of '%s' has been removed\n", args[0])
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading log: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+
+		keep := func(line string) bool {
+			if logsStream != "" && !strings.Contains(line, " "+logsStream+" | ") && !strings.Contains(line, " # | ") {
+				return false
+			}
+			return pattern == nil || pattern.MatchString(line)
+		}
+
+		reader := bufio.NewReader(f)
+		var lines []string
+		finished := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err == io.EOF {
+				// Keep a partial line for the next read while following
+				if line != "" {
+					lines = append(lines, line)
+				}
+				break
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading log: %v\n", err)
+				os.Exit(1)
+			}
+			if isRunFooter(line) {
+				finished = true
+			}
+			if keep(line) {
+				lines = append(lines, line)
+			}
+		}
+
+		if logsTail > 0 && len(lines) > logsTail {
+			lines = lines[len(lines)-logsTail:]
+		}
+		fmt.Print(strings.Join(lines, ""))
+
+		if !logsFollow || finished {
+			return
+		}
+
+		// Poll for new lines until the run writes its exit status
+		var partial string
+		for {
+			line, err := reader.ReadString('\n')
+			if err == io.EOF {
+				partial += line
+				time.Sleep(250 * time.Millisecond)
+				continue
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading log: %v\n", err)
+				os.Exit(1)
+			}
+
+			line, partial = partial+line, ""
+			if keep(line) {
+				fmt.Print(line)
+			}
+			if isRunFooter(line) {
+				return
+			}
+		}
+	},
+}
+
+// isRunFooter reports whether a log line is the exit status written at the end of a run
+func isRunFooter(line string) bool {
+	return strings.Contains(line, " # | exit ")
+}
+
+// findCapturedRun resolves a history ID or snip name to a log file name. A snip
+// name selects its most recent log, which may belong to a run still in progress.
+func findCapturedRun(entries []*history.Entry, ref string) (string, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		entry, err := history.Find(entries, id)
+		if err != nil {
+			return "", err
+		}
+		if entry.Log == "" {
+			return "", fmt.Errorf("output of run #%d was not captured (use 'sniprun run --capture')", id)
+		}
+		return entry.Log, nil
+	}
+
+	name, err := runlog.Latest(GetConfigDir(), ref)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", fmt.Errorf("no captured output for '%s' (use 'sniprun run --capture')", ref)
+	}
+	return name, nil
+}
+
+func listCapturedRuns(entries []*history.Entry) {
+	var captured []*history.Entry
+	for _, e := range entries {
+		if e.Log != "" {
+			captured = append(captured, e)
+		}
+	}
+
+	if len(captured) == 0 {
+		fmt.Println("No captured output yet. Use 'sniprun run --capture' or set capture_output: true in the config.")
+		return
+	}
+
+	if len(captured) > 20 {
+		captured = captured[len(captured)-20:]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tWHEN\tEXIT\tSNIP\tSOURCE")
+	for _, e := range captured {
+		source := "run"
+		if e.Schedule != "" {
+			source = "schedule " + e.Schedule
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\n",
+			e.ID,
+			e.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			e.ExitCode,
+			formatInvocation(e),
+			source,
+		)
+	}
+	w.Flush()
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mini-page/sniprun/internal/policy"
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	policyTestSnip     string
+	policyTestCategory string
+	policyTestTrust    string
+)
+
+func init() {
+	policyTestCmd.Flags().StringVarP(&policyTestSnip, "snip", "s", "", "Take category and trust from a snip")
+	policyTestCmd.Flags().StringVar(&policyTestCategory, "category", "", "Category to test with")
+	policyTestCmd.Flags().StringVar(&policyTestTrust, "trust", "local", "Trust level to test with (local, community, verified)")
+
+	policyCmd.AddCommand(policyTestCmd, policyShowCmd)
+	rootCmd.AddCommand(policyCmd)
+}
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Inspect the security policy",
+	Long: `Inspect the security policy.
+
+The policy is read from ` + policy.SystemPath + ` and policy.yaml in the config
+directory. Rules are evaluated in order, system rules first, and the first
+matching rule decides:
+
+  settings:
+    community_requires_validation: true
+    forbid_skip_check: true
+  rules:
+    - id: no-prod-db
+      action: deny                 # deny | warn | allow
+      pattern: 'psql .*prod'       # regular expression on the command
+      reason: Use the bastion for production databases
+    - action: warn
+      command: kubectl             # any command in the line
+    - action: allow
+      category: git
+      trust: local`,
+}
+
+var policyTestCmd = &cobra.Command{
+	Use:   "test [command]",
+	Short: "Show which policy rule decides a command",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		target := &policy.Target{
+			Command:  args[0],
+			Category: policyTestCategory,
+			Trust:    policyTestTrust,
+		}
+
+		if policyTestSnip != "" {
+			s, _, err := snip.FindSnip(GetConfigDir(), policyTestSnip)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			target.Category = s.Category
+			target.Trust = s.Trust
+		}
+
+		p := getPolicy()
+		decision := p.Evaluate(target)
+
+		fmt.Printf("Command: %s\n", target.Command)
+		fmt.Printf("Category: %s, trust: %s\n", orNone(target.Category), orNone(target.Trust))
+		fmt.Printf("Confirmation: %s\n\n", p.Confirmation(target.Trust))
+
+		if decision.Rule == nil {
+			fmt.Println("Decision: no rule matched")
+			fmt.Printf("The command is checked by the '%s' validator\n", getValidator().Name())
+			return
+		}
+
+		fmt.Printf("Decision: %s\n", decision.Action)
+		fmt.Printf("Rule: %s\n", decision.Rule.ID)
+		fmt.Printf("Source: %s\n", decision.Rule.Source)
+		if decision.Rule.Reason != "" {
+			fmt.Printf("Reason: %s\n", decision.Rule.Reason)
+		}
+
+		switch decision.Action {
+		case policy.Allow:
+			if p.RequiresValidation(target.Trust) {
+				fmt.Println("\nCommunity snips require validation, so the validator still runs")
+			}
+		case policy.Warn:
+			fmt.Println("\nThe validator still runs and you will be asked to confirm")
+		}
+	},
+}
+
+var policyShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the loaded policy files, settings and rules",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		p := getPolicy()
+
+		if len(p.Files) == 0 {
+			fmt.Printf("No policy files found. Create %s to add rules.\n", policy.UserPath(GetConfigDir()))
+			return
+		}
+
+		fmt.Println("Policy files:")
+		for _, f := range p.Files {
+			fmt.Printf("  %s\n", f)
+		}
+
+		fmt.Println("\nSettings:")
+		fmt.Printf("  community_requires_validation: %t\n", p.CommunityRequiresValidation)
+		fmt.Printf("  forbid_skip_check: %t\n", p.ForbidSkipCheck)
+		fmt.Printf("  sandbox_trust: %s\n", orNone(strings.Join(p.SandboxTrust, ", ")))
+		fmt.Printf("  allow_yes: %s\n", orNone(strings.Join(p.AllowYes, ", ")))
+		for _, trust := range []string{"local", "community", "verified"} {
+			fmt.Printf("  trust.%s.confirm: %s\n", trust, p.Confirmation(trust))
+		}
+
+		if len(p.Rules) == 0 {
+			fmt.Println("\nNo rules")
+			return
+		}
+
+		fmt.Println("\nRules:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, r := range p.Rules {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", r.Action, r.ID, r.Reason)
+		}
+		w.Flush()
+	},
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mini-page/sniprun/config"
+	"github.com/mini-page/sniprun/internal/prompt"
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+// profileEnv selects a profile like --profile
+const profileEnv = "SNIPRUN_PROFILE"
+
+// profileName is the active profile, empty for none
+var profileName string
+
+func init() {
+	profileCmd.AddCommand(profileListCmd, profileShowCmd)
+	rootCmd.AddCommand(profileCmd)
+}
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Inspect the profiles defined in the config",
+	Long: `Profiles are environments such as dev, staging and prod defined in
+config.yaml. Select one with --profile or $` + profileEnv + `:
+
+  default_profile: dev
+  profiles:
+    prod:
+      color: red
+      vars:
+        db_url: postgres://db.prod.internal/app
+      always_confirm: true
+      forbid_categories: [docker]
+      require_sandbox: true
+
+Snips use the variables like arguments, e.g. 'psql {{db_url}}'.`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles and their safeguards",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		profiles := GetConfig().Profiles
+		if len(profiles) == 0 {
+			fmt.Println("No profiles defined. Add them under 'profiles' in config.yaml.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tVARS\tSAFEGUARDS")
+		for _, name := range profileNames() {
+			p := profiles[name]
+			marker := ""
+			if name == profileName {
+				marker = " *"
+			}
+			fmt.Fprintf(w, "%s%s\t%d\t%s\n", name, marker, len(p.Vars), orDash(strings.Join(safeguards(&p), ", ")))
+		}
+		w.Flush()
+	},
+}
+
+var profileShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show the variables and safeguards of a profile, the active one by default",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := profileName
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if name == "" {
+			fmt.Fprintf(os.Stderr, "Error: no profile selected; use --profile or name one\n")
+			os.Exit(1)
+		}
+		p, err := findProfile(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Profile: %s\n", name)
+		if p.Color != "" {
+			fmt.Printf("Colour: %s\n", p.Color)
+		}
+		fmt.Printf("Safeguards: %s\n", orDash(strings.Join(safeguards(p), ", ")))
+
+		if len(p.Vars) > 0 {
+			fmt.Println("\nVariables:")
+			keys := make([]string, 0, len(p.Vars))
+			for k := range p.Vars {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Printf("  {{%s}} = %s\n", k, p.Vars[k])
+			}
+		}
+	},
+}
+
+// selectProfile picks the profile from --profile, $SNIPRUN_PROFILE or the
+// config's default and shows it in front of every question
+func selectProfile() error {
+	if profileName == "" {
+		profileName = os.Getenv(profileEnv)
+	}
+	if profileName == "" {
+		profileName = GetConfig().DefaultProfile
+	}
+	if profileName == "" {
+		return nil
+	}
+
+	p, err := findProfile(profileName)
+	if err != nil {
+		return err
+	}
+	if err := prompt.SetBanner("["+profileName+"]", p.Color); err != nil {
+		return fmt.Errorf("profile %s: %w", profileName, err)
+	}
+	return nil
+}
+
+// findProfile looks up a profile by name
+func findProfile(name string) (*config.Profile, error) {
+	p, ok := GetConfig().Profiles[name]
+	if !ok {
+		if len(GetConfig().Profiles) == 0 {
+			return nil, fmt.Errorf("profile '%s' not found, no profiles are defined in config.yaml", name)
+		}
+		return nil, fmt.Errorf("profile '%s' not found, available: %s", name, strings.Join(profileNames(), ", "))
+	}
+	return &p, nil
+}
+
+// applyProfile gives a snip the variables of a profile, failing if the
+// profile forbids the snip's category or the command uses a variable the
+// profile does not define. An empty name applies no profile.
+func applyProfile(s *snip.Snip, name string) error {
+	if name == "" {
+		return checkVars(s)
+	}
+
+	p, err := findProfile(name)
+	if err != nil {
+		return err
+	}
+	s.UseProfile(name, p.Vars)
+
+	for _, category := range p.ForbidCategories {
+		if s.Category != "" && strings.EqualFold(category, s.Category) {
+			return fmt.Errorf("the %s profile forbids %s snips", name, s.Category)
+		}
+	}
+	return checkVars(s)
+}
+
+// checkVars fails if the snip uses placeholders that are neither arguments
+// nor variables of its profile. Without profiles in the config nothing is
+// checked, since the braces may be meant literally.
+func checkVars(s *snip.Snip) error {
+	if len(GetConfig().Profiles) == 0 {
+		return nil
+	}
+
+	undefined := s.UndefinedVars()
+	if len(undefined) == 0 {
+		return nil
+	}
+	for i, name := range undefined {
+		undefined[i] = "{{" + name + "}}"
+	}
+	if s.Profile == "" {
+		return fmt.Errorf("%s uses %s, select a profile that defines it with --profile", s.Name, strings.Join(undefined, ", "))
+	}
+	return fmt.Errorf("%s uses %s, which the %s profile does not define", s.Name, strings.Join(undefined, ", "), s.Profile)
+}
+
+// profileOf returns the profile a snip was loaded with, or nil
+func profileOf(s *snip.Snip) *config.Profile {
+	if s.Profile == "" {
+		return nil
+	}
+	p, ok := GetConfig().Profiles[s.Profile]
+	if !ok {
+		return nil
+	}
+	return &p
+}
+
+// alwaysConfirm reports whether a snip is confirmed before every run, by
+// its own marking or its profile
+func alwaysConfirm(s *snip.Snip) bool {
+	p := profileOf(s)
+	return s.NeedsConfirmation() || (p != nil && p.AlwaysConfirm)
+}
+
+// profileSandbox reports whether the profile of a snip requires the sandbox
+func profileSandbox(s *snip.Snip) bool {
+	p := profileOf(s)
+	return p != nil && p.RequireSandbox
+}
+
+// showProfile prints the banner of the active profile before a run
+func showProfile(s *snip.Snip) {
+	if s.Profile != "" {
+		fmt.Fprintf(os.Stderr, "Profile: %s\n", prompt.Banner)
+	}
+}
+
+// safeguards describes the safety settings of a profile
+func safeguards(p *config.Profile) []string {
+	var parts []string
+	if p.AlwaysConfirm {
+		parts = append(parts, "always confirm")
+	}
+	if len(p.ForbidCategories) > 0 {
+		parts = append(parts, "forbids "+strings.Join(p.ForbidCategories, ", "))
+	}
+	if p.RequireSandbox {
+		parts = append(parts, "sandbox")
+	}
+	return parts
+}
+
+func profileNames() []string {
+	names := make([]string, 0, len(GetConfig().Profiles))
+	for name := range GetConfig().Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
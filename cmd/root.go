@@ -1,58 +1,166 @@
-package cmd
-
-import (
-	"fmt"
-	"os"
-	"path/filepath"
-
-	"github.com/spf13/cobra"
-)
-
-var (
-	configDir string
-	rootCmd   = &cobra.Command{
-		Use:   "sniprun",
-		Short: "Run complex commands with short, memorable snips",
-		Long:  `sniprun - Execute simplified aliases for complex commands with community contributions`,
-	}
-)
-
-func Execute() error {
-	return rootCmd.Execute()
-}
-
-func init() {
-	cobra.OnInitialize(initConfig)
-	
-	// Global flags
-	rootCmd.PersistentFlags().StringVar(&configDir, "config", "", "config directory (default is $HOME/.sniprun)")
-}
-
-func initConfig() {
-	if configDir == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
-			os.Exit(1)
-		}
-		configDir = filepath.Join(home, ".sniprun")
-	}
-
-	// Create config directories if they don't exist
-	dirs := []string{
-		configDir,
-		filepath.Join(configDir, "snips", "local"),
-		filepath.Join(configDir, "snips", "community"),
-	}
-
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			fmt.Fprintf(os.Stderr, "Error creating directory %s: %v\n", dir, err)
-			os.Exit(1)
-		}
-	}
-}
-
-func GetConfigDir() string {
-	return configDir
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mini-page/sniprun/config"
+	"github.com/mini-page/sniprun/internal/policy"
+	"github.com/mini-page/sniprun/internal/prompt"
+	"github.com/mini-page/sniprun/internal/security"
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	configDir string
+	cfg       *config.Config
+	validator security.Validator
+	pol       *policy.Policy
+	rootCmd   = &cobra.Command{
+		Use:   "sniprun",
+		Short: "Run complex commands with short, memorable snips",
+		Long:  `sniprun - Execute simplified aliases for complex commands with community contributions`,
+	}
+)
+
+func Execute() error {
+	return rootCmd.Execute()
+}
+
+func init() {
+	cobra.OnInitialize(initConfig)
+	
+	// Global flags
+	rootCmd.PersistentFlags().StringVar(&configDir, "config", "", "config directory (default is $HOME/.sniprun)")
+	rootCmd.PersistentFlags().BoolVarP(&prompt.AssumeYes, "yes", "y", false, "Answer yes/no questions with yes (risky runs only where the security policy allows it)")
+	rootCmd.PersistentFlags().BoolVar(&prompt.NoInput, "no-input", false, "Never ask questions, exit with status 3 when one needs an answer (also $SNIPRUN_NONINTERACTIVE)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Profile whose variables and safeguards apply (also $"+profileEnv+")")
+}
+
+func initConfig() {
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+			os.Exit(1)
+		}
+		configDir = filepath.Join(home, ".sniprun")
+	}
+
+	// Create config directories if they don't exist
+	dirs := []string{
+		configDir,
+		filepath.Join(configDir, "snips", "local"),
+		filepath.Join(configDir, "snips", "community"),
+	}
+
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating directory %s: %v\n", dir, err)
+			os.Exit(1)
+		}
+	}
+
+	// A config that does not parse would silently drop settings such as
+	// the validator, so it is an error like a broken policy
+	loaded, err := config.Load(configDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", filepath.Join(configDir, "config.yaml"), err)
+		os.Exit(1)
+	}
+	cfg = loaded
+
+	if err := selectProfile(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// exitWithError reports an error and exits, with prompt.ExitNoInput when a
+// question could not be asked
+func exitWithError(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if errors.Is(err, prompt.ErrNoInput) {
+		os.Exit(prompt.ExitNoInput)
+	}
+	os.Exit(1)
+}
+
+func GetConfigDir() string {
+	return configDir
+}
+
+// GetConfig returns the settings loaded from the config directory
+func GetConfig() *config.Config {
+	return cfg
+}
+
+// SetValidator replaces the security validator used by all commands, so
+// tests can inject a fake instead of calling a remote service
+func SetValidator(v security.Validator) {
+	validator = v
+}
+
+// getValidator returns the validator selected in the config, exiting if
+// the config names one that cannot be built
+func getValidator() security.Validator {
+	if validator == nil {
+		v, err := security.FromConfig(GetConfig())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if ttl := GetConfig().CacheTTL; ttl > 0 {
+			v = security.NewCache(v, security.CacheDir(GetConfigDir()), ttl, getPolicy().Version)
+		}
+		validator = v
+	}
+	return validator
+}
+
+// getPolicy returns the system and user security policy, exiting if a
+// policy file is invalid
+func getPolicy() *policy.Policy {
+	if pol == nil {
+		p, err := policy.Load(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		pol = p
+	}
+	return pol
+}
+
+// securityRequest describes a command from a snip for validation
+func securityRequest(s *snip.Snip, command, masked string) *security.Request {
+	if masked == command {
+		masked = ""
+	}
+	return &security.Request{
+		Command:  command,
+		Masked:   masked,
+		Snip:     s.Name,
+		Category: s.Category,
+		Trust:    s.Trust,
+
+		Capabilities: securityCapabilities(s.Capabilities),
+	}
+}
+
+// securityCapabilities converts a snip's declaration for the analyzer
+func securityCapabilities(c *snip.Capabilities) *security.Capabilities {
+	if c == nil {
+		return nil
+	}
+	return &security.Capabilities{
+		Network:    c.Network,
+		Writes:     c.Writes,
+		Reads:      c.Reads,
+		Privileged: c.Privileged,
+	}
 }
\ No newline at end of file
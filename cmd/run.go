@@ -1,77 +1,714 @@
-package cmd
-
-import (
-	"fmt"
-	"os"
-
-	"github.com/mini-page/sniprun/internal/security"
-	"github.com/mini-page/sniprun/internal/snip"
-
-	"github.com/spf13/cobra"
-)
-
-var (
-	sourceMode bool
-	skipSecurityCheck bool
-)
-
-func init() {
-	runCmd.Flags().BoolVar(&sourceMode, "source", false, "Output command for shell evaluation (use with eval)")
-	runCmd.Flags().BoolVar(&skipSecurityCheck, "skip-check", false, "Skip security validation")
-	rootCmd.AddCommand(runCmd)
-}
-
-var runCmd = &cobra.Command{
-	Use:   "run [snip-name] [args...]",
-	Short: "Execute a snip",
-	Long:  `Execute a stored command snip with optional arguments`,
-	Args:  cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		snipName := args[0]
-		snipArgs := args[1:]
-
-		// Find the snip
-		s, _, err := snip.FindSnip(GetConfigDir(), snipName)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			fmt.Fprintf(os.Stderr, "Run 'sniprun list' to see available snips\n")
-			os.Exit(1)
-		}
-
-		// Interpolate arguments
-		command, err := s.InterpolateArgs(snipArgs)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Security validation (unless skipped)
-		if !skipSecurityCheck {
-			result, err := security.ValidateCommand(command)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: Security check failed: %v\n", err)
-				fmt.Fprintf(os.Stderr, "Continuing anyway (use --skip-check to suppress this warning)\n")
-			} else if result.RiskLevel == security.RiskDangerous {
-				fmt.Fprintf(os.Stderr, "❌ BLOCKED: This command appears dangerous\n")
-				fmt.Fprintf(os.Stderr, "Reason: %s\n", result.Reason)
-				fmt.Fprintf(os.Stderr, "Command: %s\n", command)
-				os.Exit(1)
-			} else if result.RiskLevel == security.RiskWarning {
-				if !security.PromptUserConfirmation(command, result.Reason) {
-					fmt.Println("Execution cancelled")
-					os.Exit(0)
-				}
-			}
-		}
-
-		// Execute
-		if sourceMode {
-			fmt.Println(command)
-		} else {
-			if err := s.Execute(snipArgs, false); err != nil {
-				fmt.Fprintf(os.Stderr, "Execution failed: %v\n", err)
-				os.Exit(1)
-			}
-		}
-	},
-}
\ No newline at end of file
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/auditlog"
+	"github.com/mini-page/sniprun/internal/history"
+	"github.com/mini-page/sniprun/internal/pin"
+	"github.com/mini-page/sniprun/internal/policy"
+	"github.com/mini-page/sniprun/internal/prompt"
+	"github.com/mini-page/sniprun/internal/runlog"
+	"github.com/mini-page/sniprun/internal/sandbox"
+	"github.com/mini-page/sniprun/internal/secrets"
+	"github.com/mini-page/sniprun/internal/security"
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	sourceMode        bool
+	skipSecurityCheck bool
+	captureOutput     bool
+	runSandboxed      bool
+)
+
+func init() {
+	runCmd.Flags().BoolVar(&sourceMode, "source", false, "Output command for shell evaluation (use with eval)")
+	runCmd.Flags().BoolVar(&skipSecurityCheck, "skip-check", false, "Skip security validation")
+	runCmd.Flags().BoolVar(&captureOutput, "capture", false, "Also save output to a log (see 'sniprun logs'); default from capture_output in config")
+	runCmd.Flags().BoolVar(&runSandboxed, "sandbox", false, "Run without network and with a read-only filesystem except the current directory (Linux)")
+	rootCmd.AddCommand(runCmd)
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run [snip-name|!id] [args...]",
+	Short: "Execute a snip",
+	Long: `Execute a stored command snip with optional arguments.
+
+Use !N to repeat entry N from 'sniprun history', !! to repeat the last run
+or !-N to repeat the Nth most recent run.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeSnipArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		snipName := args[0]
+		snipArgs := args[1:]
+
+		if strings.HasPrefix(snipName, "!") {
+			if len(snipArgs) > 0 {
+				fmt.Fprintf(os.Stderr, "Error: arguments cannot be combined with %s\n", snipName)
+				os.Exit(1)
+			}
+
+			entry, err := resolveHistoryRef(snipName)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			snipName, snipArgs = recallInvocation(entry)
+		}
+
+		runSnip(snipName, snipArgs)
+	},
+}
+
+// runSnip validates and executes a snip, recording the run in the history
+func runSnip(snipName string, snipArgs []string) {
+	// Find the snip
+	s, path, err := snip.FindSnip(GetConfigDir(), snipName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Run 'sniprun list' to see available snips\n")
+		os.Exit(1)
+	}
+	if err := applyProfile(s, profileName); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	showProfile(s)
+
+	// Interpolate arguments, asking for secrets not given
+	values, err := resolveValues(s, snipArgs, true)
+	if err != nil {
+		exitWithError(err)
+	}
+	command, err := s.InterpolateArgs(values.Args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Secret values are never shown, audited or sent to a validator
+	shown := values.shown(s)
+
+	// Security validation (unless skipped and the policy allows it)
+	var result *security.ValidationResult
+	var overrides []string
+	if skipSecurityCheck {
+		checkSkip(s, values)
+		overrides = append(overrides, "skip-check")
+	} else {
+		result = checkCommand(s, values)
+	}
+	checkPin(s, path)
+
+	// Execute
+	if sourceMode {
+		// The command would run in the caller's shell, outside any sandbox
+		if runSandboxed || sandboxReason(s) != "" {
+			fmt.Fprintf(os.Stderr, "Error: sandboxed snips cannot be used with --source\n")
+			os.Exit(1)
+		}
+		exports, err := values.exports()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		record := auditRecord(auditlog.EventSource, s, shown, result)
+		record.Overrides = overrides
+		requireAudit(record)
+
+		fmt.Println(exports + command)
+	} else {
+		opts := snip.ExecOptions{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr, Env: values.Env}
+		opts.Sandbox, err = sandboxFor(s, "", runSandboxed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		checkAudit()
+
+		var runLog *runlog.Log
+		if captureOutput || GetConfig().CaptureOutput {
+			runLog = startCapture(s, values, &opts, os.Stderr)
+		}
+
+		fmt.Printf("Executing: %s\n", shown)
+
+		cwd, _ := os.Getwd()
+		started := time.Now()
+		err := s.Run(command, opts)
+
+		entry := history.NewEntry(s, values.Args, cwd, started, err)
+		finishRun(entry, runLog, os.Stderr)
+
+		record := auditRecord(auditlog.EventRun, s, shown, result)
+		record.Overrides = overrides
+		record.ExitCode = &entry.ExitCode
+		if opts.Sandbox != nil {
+			record.Detail = "sandboxed"
+		}
+		requireAudit(record)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Execution failed: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// sandboxFor returns the sandbox a snip runs in, or nil. The policy can
+// force the sandbox for a trust level and a profile for all snips. Snips
+// that declare capabilities get exactly those, others get the configured
+// network setting with dir writable.
+func sandboxFor(s *snip.Snip, dir string, requested bool) (*sandbox.Config, error) {
+	forced := sandboxReason(s)
+	if !requested && forced == "" {
+		return nil, nil
+	}
+
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+
+	c := GetConfig().Sandbox
+	allowNetwork := c.AllowNetwork
+	writable := []string{dir}
+	if caps := s.Capabilities; caps != nil {
+		if caps.Privileged {
+			return nil, fmt.Errorf("%s declares privileged access, which the sandbox does not grant", s.Name)
+		}
+		allowNetwork = caps.Network
+		writable = caps.WritablePaths(dir)
+	}
+
+	network := "no network"
+	if allowNetwork {
+		network = "network allowed"
+	}
+	files := "read-only filesystem"
+	if len(writable) > 0 {
+		files += " except " + strings.Join(writable, ", ")
+	}
+	reason := ""
+	if forced != "" {
+		reason = fmt.Sprintf(" (%s)", forced)
+	}
+	fmt.Fprintf(os.Stderr, "🔒 Sandbox: %s, %s%s\n", network, files, reason)
+
+	return &sandbox.Config{
+		AllowNetwork: allowNetwork,
+		Writable:     writable,
+		Strict:       c.Strict,
+		Limits: sandbox.Limits{
+			CPUSeconds: c.CPUSeconds,
+			FileSize:   c.FileSize,
+			OpenFiles:  c.OpenFiles,
+			Processes:  c.Processes,
+		},
+	}, nil
+}
+
+// sandboxReason tells why a snip has to run in the sandbox, or is empty if
+// it does not have to
+func sandboxReason(s *snip.Snip) string {
+	switch {
+	case getPolicy().SandboxRequired(s.Trust):
+		return fmt.Sprintf("required for %s snips by the policy", s.Trust)
+	case profileSandbox(s):
+		return fmt.Sprintf("required by the %s profile", s.Profile)
+	}
+	return ""
+}
+
+// checkCommand validates a command before it is executed, exiting if it is
+// blocked or the user declines a warning. The result is nil if the check
+// failed.
+func checkCommand(s *snip.Snip, values *snipValues) *security.ValidationResult {
+	command := values.shown(s)
+	result, err := validateCommand(s, values.command(s), values)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Security check failed: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Continuing anyway (use --skip-check to suppress this warning)\n")
+		if alwaysConfirm(s) {
+			confirmCommand(s, values, nil, confirmationReason(s, nil))
+		}
+		return nil
+	}
+
+	if result.RiskLevel == security.RiskDangerous {
+		recordAudit(auditRecord(auditlog.EventBlocked, s, command, result), os.Stderr)
+		fmt.Fprintf(os.Stderr, "❌ BLOCKED: This command appears dangerous\n")
+		printReasons(os.Stderr, result)
+		fmt.Fprintf(os.Stderr, "Command: %s\n", command)
+		os.Exit(1)
+	} else if needsConfirmation(s, result.RiskLevel) {
+		confirmCommand(s, values, result, confirmationReason(s, result))
+	}
+	return result
+}
+
+// confirmCommand asks the user to confirm a command, recording the answer
+// in the audit log and exiting if they decline
+func confirmCommand(s *snip.Snip, values *snipValues, result *security.ValidationResult, reason string) {
+	command := values.shown(s)
+	record := auditRecord(auditlog.EventApproved, s, command, result)
+	record.Detail = reason
+
+	approved, err := promptConfirmation(s, values, command, reason)
+	if !approved {
+		record.Event = auditlog.EventDeclined
+	} else if prompt.AssumeYes {
+		record.Overrides = []string{"yes"}
+	}
+	requireAudit(record)
+
+	if err != nil {
+		exitWithError(err)
+	}
+
+	if !approved {
+		fmt.Println("Execution cancelled")
+		os.Exit(0)
+	}
+}
+
+// promptConfirmation asks whether to run a command. Snips marked confirm or
+// destructive can phrase the question themselves, and destructive snips are
+// confirmed by typing their confirmation text rather than yes. --yes answers
+// for the user if the policy allows it for the snip's trust level.
+func promptConfirmation(s *snip.Snip, values *snipValues, command, reason string) (bool, error) {
+	if prompt.AssumeYes {
+		if err := getPolicy().CheckYes(s.Trust); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if !prompt.Interactive() {
+		return false, fmt.Errorf("%w: '%s' needs confirmation (%s)", prompt.ErrNoInput, s.Name, reason)
+	}
+	if !s.NeedsConfirmation() {
+		return security.PromptUserConfirmation(command, reason)
+	}
+
+	if s.Destructive {
+		fmt.Printf("\n⚠️  WARNING: '%s' is marked destructive\n", s.Name)
+	} else {
+		fmt.Printf("\n⚠️  '%s' asks for confirmation\n", s.Name)
+	}
+	fmt.Printf("Command: %s\n", command)
+	fmt.Printf("Reason: %s\n\n", reason)
+	if s.Prompt != "" {
+		fmt.Println(s.Prompt)
+	}
+
+	if s.Destructive {
+		expected := s.TypedConfirmation(values.Args)
+		answer, err := prompt.Line(fmt.Sprintf("Type '%s' to continue: ", expected))
+		return err == nil && strings.TrimSpace(answer) == expected, err
+	}
+
+	fmt.Print(prompt.Label("Do you want to continue? (yes/no): "))
+	answer, err := prompt.ReadLine()
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "yes" || answer == "y", err
+}
+
+// needsConfirmation reports whether a command with a risk level has to be
+// confirmed. Snips marked confirm or destructive and snips run with a
+// profile that confirms every run always are, otherwise the policy decides
+// per trust level.
+func needsConfirmation(s *snip.Snip, level security.RiskLevel) bool {
+	if alwaysConfirm(s) {
+		return true
+	}
+
+	switch getPolicy().Confirmation(s.Trust) {
+	case policy.ConfirmAlways:
+		return true
+	case policy.ConfirmNever:
+		return false
+	}
+	return level == security.RiskWarning
+}
+
+// confirmationReason explains why a command needs confirmation
+func confirmationReason(s *snip.Snip, result *security.ValidationResult) string {
+	switch {
+	case result != nil && result.RiskLevel == security.RiskWarning:
+		return reasonText(result)
+	case s.Destructive:
+		return "The snip is marked destructive"
+	case s.Confirm:
+		return "The snip asks for confirmation before every run"
+	case alwaysConfirm(s):
+		return fmt.Sprintf("The %s profile asks for confirmation before every run", s.Profile)
+	}
+	return fmt.Sprintf("The security policy requires confirmation for %s snips", s.Trust)
+}
+
+// checkPin makes sure a community snip's command is the one that was run or
+// approved before. The first run pins the command; once an update changes
+// it, the user sees a diff and has to approve the new command.
+func checkPin(s *snip.Snip, path string) {
+	if !snip.IsCommunity(GetConfigDir(), path) {
+		return
+	}
+
+	pins, err := pin.Load(GetConfigDir())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if p := pins[s.Name]; p != nil {
+		if p.Matches(s.Command) {
+			return
+		}
+
+		fmt.Printf("\n⚠️  The command of community snip '%s' changed since you approved it on %s:\n\n",
+			s.Name, p.Approved.Format("2006-01-02"))
+		for _, line := range pin.Diff(p.Command, s.Command) {
+			fmt.Printf("  %s\n", line)
+		}
+		fmt.Println()
+
+		// --yes only approves the change where it may skip confirmations
+		if prompt.AssumeYes {
+			if err := getPolicy().CheckYes(s.Trust); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		approved, err := prompt.Confirm("Approve the new command?")
+
+		record := auditRecord(auditlog.EventApproved, s, s.Command, nil)
+		if !approved {
+			record.Event = auditlog.EventDeclined
+		}
+		if prompt.AssumeYes {
+			record.Overrides = []string{"yes"}
+		}
+		record.Detail = "changed community snip command"
+		requireAudit(record)
+
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if !approved {
+			fmt.Println("Execution cancelled")
+			os.Exit(0)
+		}
+	}
+
+	pins[s.Name] = pin.New(s.Command)
+	if err := pin.Save(GetConfigDir(), pins); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// checkSkip exits if the security policy does not allow skipping the check
+// for a snip. Snips that always need confirmation still ask for it.
+func checkSkip(s *snip.Snip, values *snipValues) {
+	if err := getPolicy().CheckSkip(s.Trust); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if alwaysConfirm(s) || getPolicy().Confirmation(s.Trust) == policy.ConfirmAlways {
+		confirmCommand(s, values, nil, confirmationReason(s, nil))
+	}
+}
+
+// validateCommand checks the command of a snip as it will run. With values,
+// remote validators only see the command with secrets masked, and secrets
+// are hidden in the reasons of the result.
+func validateCommand(s *snip.Snip, command string, values *snipValues) (*security.ValidationResult, error) {
+	masked := command
+	if values != nil {
+		masked = values.shown(s)
+	}
+
+	result, err := evaluateCommand(s, command, masked)
+	if err != nil || values == nil || len(values.Secrets) == 0 {
+		return result, err
+	}
+
+	result.Reason = secrets.Redact(result.Reason, values.Secrets)
+	for i := range result.Findings {
+		result.Findings[i].Message = secrets.Redact(result.Findings[i].Message, values.Secrets)
+	}
+	for i := range result.Verdicts {
+		result.Verdicts[i].Reason = secrets.Redact(result.Verdicts[i].Reason, values.Secrets)
+		result.Verdicts[i].Error = secrets.Redact(result.Verdicts[i].Error, values.Secrets)
+	}
+	return result, nil
+}
+
+// evaluateCommand evaluates the security policy and, unless a rule settles
+// it, the configured validator. The policy rule that matched is listed with
+// the validators' verdicts, as is a trust level the snip claims but was not
+// loaded with.
+func evaluateCommand(s *snip.Snip, command, masked string) (*security.ValidationResult, error) {
+	p := getPolicy()
+	decision := p.Evaluate(&policy.Target{Command: command, Category: s.Category, Trust: s.Trust})
+
+	// Verdicts that raise the result to at least a warning
+	var raised []security.Verdict
+	if s.DeclaredTrust != "" {
+		raised = append(raised, security.Verdict{
+			Validator: "trust",
+			RiskLevel: security.RiskWarning,
+			Reason:    fmt.Sprintf("The snip claims trust '%s' but was loaded as a %s snip", s.DeclaredTrust, s.Trust),
+		})
+	}
+
+	policyVerdicts := raised
+	if decision.Rule != nil {
+		verdict := security.Verdict{
+			Validator: "policy",
+			RiskLevel: policyLevels[decision.Action],
+			Reason:    fmt.Sprintf("%s by rule %s", policyActions[decision.Action], decision.Rule),
+		}
+		policyVerdicts = append(policyVerdicts, verdict)
+		if decision.Action == policy.Warn {
+			raised = append(raised, verdict)
+		}
+	}
+
+	switch decision.Action {
+	case policy.Deny:
+		return policyResult(security.RiskDangerous, policyVerdicts), nil
+	case policy.Allow:
+		if !p.RequiresValidation(s.Trust) && len(raised) == 0 {
+			return policyResult(security.RiskSafe, policyVerdicts), nil
+		}
+	}
+
+	result, err := getValidator().Validate(securityRequest(s, command, masked))
+	if err != nil {
+		if p.RequiresValidation(s.Trust) {
+			return policyResult(security.RiskDangerous, append(policyVerdicts, security.Verdict{
+				Validator: "policy",
+				RiskLevel: security.RiskDangerous,
+				Reason:    fmt.Sprintf("Community snips require validation, but the security check failed: %v", err),
+			})), nil
+		}
+		return nil, err
+	}
+
+	if len(policyVerdicts) == 0 {
+		return result, nil
+	}
+
+	verdicts := append(policyVerdicts, result.Contributions()...)
+	if len(raised) > 0 && result.RiskLevel.Severity() < security.RiskWarning.Severity() {
+		result = policyResult(security.RiskWarning, raised)
+	}
+	result.Verdicts = verdicts
+	return result, nil
+}
+
+var policyLevels = map[policy.Action]security.RiskLevel{
+	policy.Deny:  security.RiskDangerous,
+	policy.Warn:  security.RiskWarning,
+	policy.Allow: security.RiskSafe,
+}
+
+var policyActions = map[policy.Action]string{
+	policy.Deny:  "Denied",
+	policy.Warn:  "Flagged",
+	policy.Allow: "Allowed",
+}
+
+// policyResult builds a result decided by the policy, taking its reason from
+// the last policy verdict
+func policyResult(level security.RiskLevel, verdicts []security.Verdict) *security.ValidationResult {
+	return &security.ValidationResult{
+		Safe:      level == security.RiskSafe,
+		RiskLevel: level,
+		Reason:    verdicts[len(verdicts)-1].Reason,
+		Validator: "policy",
+		Verdicts:  verdicts,
+	}
+}
+
+// printReasons lists the reason of every validator that contributed to a
+// result
+func printReasons(w io.Writer, result *security.ValidationResult) {
+	lines := result.Explanation()
+	if len(lines) == 1 {
+		fmt.Fprintf(w, "Reason: %s\n", result.Reason)
+		return
+	}
+
+	fmt.Fprintln(w, "Reasons:")
+	for _, line := range lines {
+		fmt.Fprintf(w, "  - %s\n", line)
+	}
+}
+
+// reasonText is printReasons for prompts that take the reason as a string
+func reasonText(result *security.ValidationResult) string {
+	lines := result.Explanation()
+	if len(lines) == 1 {
+		return result.Reason
+	}
+	return "\n  - " + strings.Join(lines, "\n  - ")
+}
+
+// startCapture tees the output configured in opts into a new run log, with
+// secret values redacted. Problems are reported to warnings and leave opts
+// untouched.
+func startCapture(s *snip.Snip, values *snipValues, opts *snip.ExecOptions, warnings io.Writer) *runlog.Log {
+	runLog, err := runlog.Create(GetConfigDir(), s.Name, GetConfig().LogMaxBytes)
+	if err != nil {
+		fmt.Fprintf(warnings, "Warning: output will not be captured: %v\n", err)
+		return nil
+	}
+
+	runLog.Hide(values.Secrets)
+	runLog.Header("$ %s", values.shown(s))
+	opts.Stdout = io.MultiWriter(opts.Stdout, runLog.Stream("out"))
+	opts.Stderr = io.MultiWriter(opts.Stderr, runLog.Stream("err"))
+	return runLog
+}
+
+// historyMu serialises history writes from concurrent scheduled runs
+var historyMu sync.Mutex
+
+// finishRun closes the run log, if any, and records the run in the history
+func finishRun(entry *history.Entry, runLog *runlog.Log, warnings io.Writer) {
+	if runLog != nil {
+		if err := runLog.Close(entry.ExitCode, entry.Duration); err != nil {
+			fmt.Fprintf(warnings, "Warning: failed to save output log: %v\n", err)
+		}
+		entry.Log = filepath.Base(runLog.Path)
+		if err := runlog.Prune(GetConfigDir(), GetConfig().LogRetention); err != nil {
+			fmt.Fprintf(warnings, "Warning: failed to remove old logs: %v\n", err)
+		}
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if err := history.Append(GetConfigDir(), entry); err != nil {
+		fmt.Fprintf(warnings, "Warning: failed to record history: %v\n", err)
+	}
+}
+
+// resolveHistoryRef looks up a history reference such as !3, !! or !-2
+func resolveHistoryRef(ref string) (*history.Entry, error) {
+	entries, err := history.Load(GetConfigDir())
+	if err != nil {
+		return nil, err
+	}
+
+	if ref == "!!" {
+		return history.Last(entries, "")
+	}
+
+	n, err := strconv.Atoi(strings.TrimPrefix(ref, "!"))
+	if err != nil || n == 0 {
+		return nil, fmt.Errorf("invalid history reference '%s' (use !N, !! or !-N)", ref)
+	}
+
+	if n < 0 {
+		if -n > len(entries) {
+			return nil, fmt.Errorf("history only has %d entries", len(entries))
+		}
+		return entries[len(entries)+n], nil
+	}
+	return history.Find(entries, n)
+}
+
+// recallInvocation returns the snip and command line arguments of a past
+// run, asking again for the values of secret arguments since those were
+// never recorded. Arguments read from a secret source are read again.
+func recallInvocation(entry *history.Entry) (string, []string) {
+	args := append([]string(nil), entry.Args...)
+	fmt.Printf("Re-running #%d: %s\n", entry.ID, formatInvocation(entry))
+
+	s, _, err := snip.FindSnip(GetConfigDir(), entry.Snip)
+	if err != nil {
+		return entry.Snip, args
+	}
+
+	for _, pos := range entry.SecretArgs {
+		if pos >= len(args) || (pos < len(s.Args) && s.Args[pos].From != "") {
+			continue
+		}
+		name := fmt.Sprintf("argument %d", pos+1)
+		if pos < len(s.Args) {
+			name = s.Args[pos].Name
+		}
+		value, err := prompt.Secret(fmt.Sprintf("Value for %s: ", name))
+		if err != nil {
+			exitWithError(err)
+		}
+		args[pos] = value
+	}
+
+	var given []string
+	for i, arg := range args {
+		if i < len(s.Args) && s.Args[i].From != "" {
+			continue
+		}
+		given = append(given, arg)
+	}
+	return entry.Snip, given
+}
+
+// completeSnipArgs completes snip names and offers previously used values for
+// their arguments
+func completeSnipArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		snips, err := snip.ListSnips(GetConfigDir())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		// Offer the snips used most often and most recently first
+		var scores map[string]float64
+		if entries, err := history.Load(GetConfigDir()); err == nil {
+			scores = history.Frecency(entries, time.Now())
+		}
+
+		var names []string
+		for _, name := range snip.SortNames(snips, scores) {
+			names = append(names, fmt.Sprintf("%s\t%s", name, snips[name].Description))
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+	}
+
+	entries, err := history.Load(GetConfigDir())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	// Arguments read from a secret source are not on the command line
+	position := len(args) - 1
+	if s, _, err := snip.FindSnip(GetConfigDir(), args[0]); err == nil {
+		positions := s.Positions()
+		if position >= len(positions) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		position = positions[position]
+	}
+
+	values := history.ArgValues(entries, args[0], position)
+	if len(values) == 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return values, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/auditlog"
+	"github.com/mini-page/sniprun/internal/prompt"
+	"github.com/mini-page/sniprun/internal/schedule"
+	"github.com/mini-page/sniprun/internal/security"
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	scheduleCron     string
+	scheduleLogsTail int
+)
+
+func init() {
+	scheduleAddCmd.Flags().StringVar(&scheduleCron, "cron", "", "Cron expression, e.g. \"0 9 * * 1-5\" (required)")
+	scheduleAddCmd.MarkFlagRequired("cron")
+	scheduleLogsCmd.Flags().IntVarP(&scheduleLogsTail, "tail", "n", 0, "Only show the last N lines")
+
+	scheduleCmd.AddCommand(scheduleAddCmd, scheduleListCmd, scheduleRemoveCmd, scheduleLogsCmd)
+	rootCmd.AddCommand(scheduleCmd)
+}
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Manage recurring snips",
+	Long:  `Schedule snips to run on a cron expression. Scheduled snips are executed by 'sniprun daemon'.`,
+}
+
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add [snip-name] [args...]",
+	Short: "Schedule a snip to run on a cron expression",
+	Long: `Schedule a snip to run on a cron expression.
+
+Scheduled snips run without a terminal, so commands the security check flags
+as risky must be approved now. The approval only covers the exact command,
+so if the snip changes the daemon will skip it until it is scheduled again.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		snipName := args[0]
+		snipArgs := args[1:]
+
+		cron, err := schedule.ParseCron(scheduleCron)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		s, path, err := snip.FindSnip(GetConfigDir(), snipName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Run 'sniprun list' to see available snips\n")
+			os.Exit(1)
+		}
+		if err := applyProfile(s, profileName); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// The daemon cannot ask for secrets and the arguments are stored
+		// with the job, so secrets have to come from a source. Only the
+		// command with secrets masked is kept for the approval.
+		for _, arg := range s.Args {
+			if arg.Secret && arg.From == "" {
+				fmt.Fprintf(os.Stderr, "Error: secret argument '%s' would be stored with the schedule; read it from a source ('from:') instead\n", arg.Name)
+				os.Exit(1)
+			}
+		}
+		values, err := resolveValues(s, snipArgs, false)
+		if err != nil {
+			exitWithError(err)
+		}
+		command := values.shown(s)
+
+		job := &schedule.Job{
+			ID:      schedule.NewID(),
+			Snip:    snipName,
+			Args:    snipArgs,
+			Cron:    scheduleCron,
+			Created: time.Now(),
+			Command: command,
+			Profile: profileName,
+		}
+
+		// Pre-approve risky commands while someone is around to answer
+		fmt.Println("Validating command security...")
+		result, err := validateCommand(s, values.command(s), values)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Security check failed: %v\n", err)
+			fmt.Fprintf(os.Stderr, "The daemon will validate the command again before each run\n")
+		} else if result.RiskLevel == security.RiskDangerous {
+			fmt.Fprintf(os.Stderr, "❌ Cannot schedule: This command appears dangerous\n")
+			printReasons(os.Stderr, result)
+			os.Exit(1)
+		}
+
+		// Snips that always need confirmation are approved now even when
+		// the check failed, since the daemon never runs them otherwise
+		if alwaysConfirm(s) || (err == nil && needsConfirmation(s, result.RiskLevel)) {
+			reason := confirmationReason(s, result)
+			approved, err := promptConfirmation(s, values, command, reason)
+
+			record := auditRecord(auditlog.EventApproved, s, command, result)
+			if !approved {
+				record.Event = auditlog.EventDeclined
+			} else if prompt.AssumeYes {
+				record.Overrides = []string{"yes"}
+			}
+			record.Schedule = job.ID
+			record.Detail = reason
+			requireAudit(record)
+
+			if err != nil {
+				exitWithError(err)
+			}
+			if !approved {
+				fmt.Println("Cancelled")
+				return
+			}
+			job.Approved = true
+		} else if err == nil {
+			fmt.Println("✓ Command validated")
+		}
+		checkPin(s, path)
+
+		err = schedule.UpdateJobs(GetConfigDir(), func(jobs []*schedule.Job) ([]*schedule.Job, error) {
+			return append(jobs, job), nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n✓ Scheduled '%s' as %s (next run: %s)\n", snipName, job.ID, formatNextRun(cron))
+		fmt.Println("Run 'sniprun daemon' to execute scheduled snips")
+	},
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled snips",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		jobs, err := schedule.LoadJobs(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(jobs) == 0 {
+			fmt.Println("No scheduled snips. Run 'sniprun schedule add <snip> --cron \"<expr>\"' to add one.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCRON\tNEXT RUN\tSNIP")
+		for _, job := range jobs {
+			next := "invalid cron"
+			if cron, err := schedule.ParseCron(job.Cron); err == nil {
+				next = formatNextRun(cron)
+			}
+
+			name := job.Snip
+			if len(job.Args) > 0 {
+				name += " " + strings.Join(job.Args, " ")
+			}
+			if job.Profile != "" {
+				name += " [" + job.Profile + "]"
+			}
+			if job.Approved {
+				name += " (risk approved)"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", job.ID, job.Cron, next, name)
+		}
+		w.Flush()
+	},
+}
+
+var scheduleRemoveCmd = &cobra.Command{
+	Use:   "remove [schedule-id]",
+	Short: "Remove a scheduled snip",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := args[0]
+
+		err := schedule.UpdateJobs(GetConfigDir(), func(jobs []*schedule.Job) ([]*schedule.Job, error) {
+			if _, err := schedule.FindJob(jobs, id); err != nil {
+				return nil, err
+			}
+
+			remaining := make([]*schedule.Job, 0, len(jobs))
+			for _, job := range jobs {
+				if job.ID != id {
+					remaining = append(remaining, job)
+				}
+			}
+			return remaining, nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Schedule '%s' removed\n", id)
+	},
+}
+
+var scheduleLogsCmd = &cobra.Command{
+	Use:   "logs [schedule-id]",
+	Short: "Show the run log of a scheduled snip",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Only IDs of scheduled jobs name a log, so the argument cannot
+		// point outside the log directory
+		jobs, err := schedule.LoadJobs(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if _, err := schedule.FindJob(jobs, args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		data, err := os.ReadFile(schedule.LogPath(GetConfigDir(), args[0]))
+		if os.IsNotExist(err) {
+			fmt.Printf("No runs recorded for '%s' yet\n", args[0])
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading log: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Print(tailLines(string(data), scheduleLogsTail))
+	},
+}
+
+// tailLines returns the last n lines of text, all of it if n is not
+// positive. A final line without a newline counts as a line.
+func tailLines(text string, n int) string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "")
+}
+
+func formatNextRun(cron *schedule.Cron) string {
+	next := cron.Next(time.Now())
+	if next.IsZero() {
+		return "never"
+	}
+	return next.Format("Mon 2006-01-02 15:04")
+}
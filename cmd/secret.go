@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mini-page/sniprun/internal/prompt"
+	"github.com/mini-page/sniprun/internal/secrets"
+	"github.com/mini-page/sniprun/internal/snip"
+	"github.com/mini-page/sniprun/internal/vault"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// vaultPassphraseEnv holds the vault passphrase for runs without a terminal
+const vaultPassphraseEnv = "SNIPRUN_VAULT_PASSPHRASE"
+
+func init() {
+	secretCmd.AddCommand(secretSetCmd, secretListCmd, secretRemoveCmd)
+	rootCmd.AddCommand(secretCmd)
+}
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage secrets in the encrypted vault",
+	Long: `Secrets are stored in an encrypted vault in the config directory and can be
+used by snip arguments and environment variables with 'from: vault:NAME'.
+
+The vault is encrypted with a key derived from a passphrase, which is asked
+for when needed or read from $` + vaultPassphraseEnv + `, unless the vault
+was unlocked with 'sniprun vault unlock'.`,
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set [name]",
+	Short: "Store a secret, reading the value without echo",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		created := !vault.Exists(GetConfigDir())
+		key, contents, err := openVault(true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// A value piped in is read as is, so secrets can be set from scripts
+		var value string
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			value, err = prompt.Secret(fmt.Sprintf("Value for %s: ", args[0]))
+		} else {
+			value, err = prompt.ReadLine()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if value == "" {
+			fmt.Fprintf(os.Stderr, "Error: the value is empty\n")
+			os.Exit(1)
+		}
+		if err := secrets.CheckLength(value); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		contents.Secrets[args[0]] = value
+		if err := vault.Save(GetConfigDir(), key, contents); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if created {
+			fmt.Printf("✓ Created vault %s\n", vault.Path(GetConfigDir()))
+		}
+		fmt.Printf("✓ Stored secret '%s', use it with 'from: vault:%s'\n", args[0], args[0])
+	},
+}
+
+var secretListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the names of stored secrets",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !vault.Exists(GetConfigDir()) {
+			fmt.Println("No secrets stored. Add one with 'sniprun secret set <name>'.")
+			return
+		}
+
+		_, contents, err := openVault(false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		names := make([]string, 0, len(contents.Secrets))
+		for name := range contents.Secrets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Println(name)
+		}
+	},
+}
+
+var secretRemoveCmd = &cobra.Command{
+	Use:   "remove [name]",
+	Short: "Remove a secret from the vault",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		key, contents, err := openVault(false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if _, ok := contents.Secrets[args[0]]; !ok {
+			fmt.Fprintf(os.Stderr, "Error: secret '%s' not found\n", args[0])
+			os.Exit(1)
+		}
+		delete(contents.Secrets, args[0])
+
+		if err := vault.Save(GetConfigDir(), key, contents); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Removed secret '%s'\n", args[0])
+	},
+}
+
+// openVault decrypts the vault with the key of the unlocked session, or
+// asks for the passphrase if it is locked. With create set a missing vault
+// is created.
+func openVault(create bool) (vault.Key, *vault.Contents, error) {
+	configDir := GetConfigDir()
+
+	var key vault.Key
+	if k, _, ok := vault.SessionKey(configDir); ok {
+		key = k
+	} else if create && !vault.Exists(configDir) {
+		passphrase, err := readPassphrase(true)
+		if err != nil {
+			return nil, nil, err
+		}
+		if key, err = vault.Create(configDir, passphrase); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if !vault.Exists(configDir) {
+			return nil, nil, vault.ErrNoVault
+		}
+		passphrase, err := readPassphrase(false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if key, err = vault.Unlock(configDir, passphrase); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	contents, err := vault.Load(configDir, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, contents, nil
+}
+
+// readPassphrase returns the vault passphrase from the environment or asks
+// for it. A new passphrase has to be entered twice.
+func readPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(vaultPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if !prompt.Interactive() {
+		return "", fmt.Errorf("%w: the vault passphrase cannot be asked for; set $%s or run 'sniprun vault unlock'", prompt.ErrNoInput, vaultPassphraseEnv)
+	}
+
+	passphrase, err := prompt.Secret("Vault passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("the vault passphrase is empty")
+	}
+
+	if confirm {
+		again, err := prompt.Secret("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("the passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+// secretResolver reads secret sources, opening the vault at most once
+func secretResolver() *secrets.Resolver {
+	var contents *vault.Contents
+	return &secrets.Resolver{
+		Vault: func() (map[string]string, error) {
+			if contents == nil {
+				var err error
+				if _, contents, err = openVault(false); err != nil {
+					return nil, err
+				}
+			}
+			return contents.Secrets, nil
+		},
+	}
+}
+
+// snipValues are the values of a snip's arguments and environment for one
+// run
+type snipValues struct {
+	// Args are lined up with the snip's arguments
+	Args []string
+	// Env holds NAME=value pairs for the command's environment
+	Env []string
+	// Secrets are the values to hide wherever the run is shown or recorded
+	Secrets []string
+}
+
+// resolveValues lines up the arguments given on the command line with the
+// snip's arguments and reads the rest from their secret sources. Secret
+// arguments left off the end of the command line and environment variables
+// without a value are asked for, unless interactive is false or questions
+// cannot be asked. Snips the user did not write are refused if they read
+// values from files or commands.
+func resolveValues(s *snip.Snip, given []string, interactive bool) (*snipValues, error) {
+	if err := s.CheckSources(); err != nil {
+		return nil, err
+	}
+
+	interactive = interactive && prompt.Interactive()
+	positions := s.Positions()
+	if len(given) > len(positions) {
+		return nil, fmt.Errorf("expected %d arguments (%v), got %d", len(positions), s.UsageNames(), len(given))
+	}
+	for _, i := range positions[len(given):] {
+		if !s.Args[i].Secret {
+			return nil, fmt.Errorf("expected %d arguments (%v), got %d", len(positions), s.UsageNames(), len(given))
+		}
+		if !interactive {
+			return nil, fmt.Errorf("%w: secret argument '%s' has to be given or read from a source ('from:')", prompt.ErrNoInput, s.Args[i].Name)
+		}
+	}
+
+	values := &snipValues{Args: make([]string, len(s.Args))}
+	resolver := secretResolver()
+
+	for k, i := range positions {
+		if k < len(given) {
+			values.Args[i] = given[k]
+			continue
+		}
+		value, err := prompt.Secret(fmt.Sprintf("Value for %s: ", s.Args[i].Name))
+		if err != nil {
+			return nil, err
+		}
+		values.Args[i] = value
+	}
+
+	for i, arg := range s.Args {
+		if arg.From != "" {
+			value, err := resolver.Resolve(arg.From)
+			if err != nil {
+				return nil, fmt.Errorf("argument '%s': %w", arg.Name, err)
+			}
+			values.Args[i] = value
+		}
+		if arg.Secret {
+			if err := secrets.CheckLength(values.Args[i]); err != nil {
+				return nil, fmt.Errorf("argument '%s': %w", arg.Name, err)
+			}
+			values.Secrets = append(values.Secrets, values.Args[i])
+		}
+	}
+
+	for _, e := range s.Env {
+		value := s.InterpolateVars(e.Value)
+		switch {
+		case e.From != "":
+			var err error
+			if value, err = resolver.Resolve(e.From); err != nil {
+				return nil, fmt.Errorf("environment variable %s: %w", e.Name, err)
+			}
+		case value == "" && !interactive:
+			return nil, fmt.Errorf("%w: environment variable %s has to be read from a source ('from:')", prompt.ErrNoInput, e.Name)
+		case value == "" && e.Secret:
+			var err error
+			if value, err = prompt.Secret(fmt.Sprintf("Value for %s: ", e.Name)); err != nil {
+				return nil, err
+			}
+		case value == "":
+			fmt.Fprintf(os.Stderr, "Value for %s: ", e.Name)
+			var err error
+			if value, err = prompt.ReadLine(); err != nil {
+				return nil, err
+			}
+		}
+
+		values.Env = append(values.Env, e.Name+"="+value)
+		if e.Secret {
+			if err := secrets.CheckLength(value); err != nil {
+				return nil, fmt.Errorf("environment variable %s: %w", e.Name, err)
+			}
+			values.Secrets = append(values.Secrets, value)
+		}
+	}
+
+	return values, nil
+}
+
+// shown is the command of the snip with the values of secret arguments
+// masked. It is what is displayed, audited and sent to remote validators.
+func (v *snipValues) shown(s *snip.Snip) string {
+	command, _ := s.InterpolateArgs(s.MaskArgs(v.Args))
+	return command
+}
+
+// command is the command of the snip as it runs, secrets included
+func (v *snipValues) command(s *snip.Snip) string {
+	command, _ := s.InterpolateArgs(v.Args)
+	return command
+}
+
+// exports returns shell statements setting the snip's environment, for
+// commands printed with --source
+func (v *snipValues) exports() (string, error) {
+	var b strings.Builder
+	for _, pair := range v.Env {
+		name, value, _ := strings.Cut(pair, "=")
+		quoted, err := syntax.Quote(value, syntax.LangPOSIX)
+		if err != nil {
+			return "", fmt.Errorf("environment variable %s: %w", name, err)
+		}
+		fmt.Fprintf(&b, "export %s=%s; ", name, quoted)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mini-page/sniprun/internal/security"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	securityCacheCmd.AddCommand(securityCacheClearCmd)
+	securityCmd.AddCommand(securityCacheCmd)
+	rootCmd.AddCommand(securityCmd)
+}
+
+var securityCmd = &cobra.Command{
+	Use:   "security",
+	Short: "Manage security validation",
+}
+
+var securityCacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage cached security verdicts",
+	Long: `Security verdicts are cached under the config directory for cache_ttl
+(24h by default), keyed by the command, the validator and the policy. When the
+validator is unavailable, for example offline, older verdicts are reused.`,
+}
+
+var securityCacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached security verdicts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		count, err := security.ClearCache(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Removed %d cached verdicts\n", count)
+	},
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/history"
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+var statsLimit int
+
+func init() {
+	statsCmd.Flags().IntVarP(&statsLimit, "limit", "n", 10, "Number of most used snips to show (0 for all)")
+	rootCmd.AddCommand(statsCmd)
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show snip usage statistics",
+	Long: `Show the most used snips ranked by frecency (how often and how recently they
+were run), with failure rates and average durations, followed by installed
+snips that have never been run and may be candidates for cleanup.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := history.Load(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		snips, err := snip.ListSnips(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading snips: %v\n", err)
+			os.Exit(1)
+		}
+
+		stats := history.Summarize(entries, time.Now())
+
+		ranked := make([]*history.Stats, 0, len(stats))
+		for _, st := range stats {
+			ranked = append(ranked, st)
+		}
+		sort.Slice(ranked, func(i, j int) bool {
+			if ranked[i].Frecency != ranked[j].Frecency {
+				return ranked[i].Frecency > ranked[j].Frecency
+			}
+			return ranked[i].Snip < ranked[j].Snip
+		})
+
+		if len(ranked) == 0 {
+			fmt.Println("No runs recorded yet")
+		} else {
+			shown := ranked
+			if statsLimit > 0 && len(shown) > statsLimit {
+				shown = shown[:statsLimit]
+			}
+
+			fmt.Printf("Most used snips (%d runs total):\n\n", len(entries))
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "SNIP\tRUNS\tFAILED\tAVG DURATION\tLAST RUN")
+			for _, st := range shown {
+				name := st.Snip
+				if _, ok := snips[name]; !ok {
+					name += " (removed)"
+				}
+				fmt.Fprintf(w, "%s\t%d\t%.0f%%\t%s\t%s\n",
+					name,
+					st.Runs,
+					st.FailureRate()*100,
+					st.AverageDuration().Round(time.Millisecond),
+					st.LastRun.Local().Format("2006-01-02 15:04"),
+				)
+			}
+			w.Flush()
+		}
+
+		var unused []string
+		for name := range snips {
+			if _, ok := stats[name]; !ok {
+				unused = append(unused, name)
+			}
+		}
+		sort.Strings(unused)
+
+		if len(unused) > 0 {
+			fmt.Printf("\nNever used (%d):\n", len(unused))
+			for _, name := range unused {
+				fmt.Printf("  %s\n", name)
+			}
+		}
+	},
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/auditlog"
+	"github.com/mini-page/sniprun/internal/keyring"
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	trustCmd.AddCommand(trustAddKeyCmd, trustListCmd, trustRemoveCmd)
+	rootCmd.AddCommand(trustCmd)
+}
+
+var trustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Manage keys trusted to sign community snips",
+	Long: `Community snips are only installed by 'sniprun update' when the repository
+manifest is signed by one of these ed25519 keys.`,
+}
+
+var trustAddKeyCmd = &cobra.Command{
+	Use:   "add-key [name] [public-key|file]",
+	Short: "Trust a base64 ed25519 public key",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, encoded := args[0], args[1]
+
+		// Accept a file holding the key as well as the key itself
+		if data, err := os.ReadFile(encoded); err == nil {
+			encoded = string(data)
+		}
+		encoded = strings.TrimSpace(encoded)
+
+		if _, err := keyring.ParseKey(encoded); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		keys, err := keyring.Load(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if _, err := keyring.Find(keys, name); err == nil {
+			fmt.Fprintf(os.Stderr, "Error: a key named '%s' already exists\n", name)
+			os.Exit(1)
+		}
+
+		key := &keyring.Key{Name: name, PublicKey: encoded, Added: time.Now()}
+		if err := keyring.Save(GetConfigDir(), append(keys, key)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		recordAudit(&auditlog.Record{Event: auditlog.EventKeyAdded, Detail: fmt.Sprintf("%s (%s)", name, key.Fingerprint())}, os.Stderr)
+		fmt.Printf("✓ Trusted key '%s' (%s)\n", name, key.Fingerprint())
+	},
+}
+
+var trustListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List trusted keys",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		keys, err := keyring.Load(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(keys) == 0 {
+			fmt.Println("No trusted keys. Add one with 'sniprun trust add-key <name> <public-key>'.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tFINGERPRINT\tADDED")
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", k.Name, k.Fingerprint(), k.Added.Format("2006-01-02"))
+		}
+		w.Flush()
+	},
+}
+
+var trustRemoveCmd = &cobra.Command{
+	Use:   "remove [name]",
+	Short: "Stop trusting a key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		keys, err := keyring.Load(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var kept []*keyring.Key
+		for _, k := range keys {
+			if k.Name != args[0] {
+				kept = append(kept, k)
+			}
+		}
+		if len(kept) == len(keys) {
+			fmt.Fprintf(os.Stderr, "Error: key '%s' not found\n", args[0])
+			os.Exit(1)
+		}
+
+		if err := keyring.Save(GetConfigDir(), kept); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Snips signed by the key are no longer verified
+		communityDir := filepath.Join(GetConfigDir(), "snips", "community")
+		if snip.LoadVerified(communityDir).Key == args[0] {
+			if err := os.Remove(filepath.Join(communityDir, snip.VerifiedFile)); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+
+		recordAudit(&auditlog.Record{Event: auditlog.EventKeyRemoved, Detail: args[0]}, os.Stderr)
+		fmt.Printf("✓ Removed key '%s'\n", args[0])
+	},
+}
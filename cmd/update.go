@@ -1,34 +1,114 @@
-package cmd
-
-import (
-	"fmt"
-	"os"
-
-	"github.com/mini-page/sniprun/internal/repo"
-
-	"github.com/spf13/cobra"
-)
-
-func init() {
-	rootCmd.AddCommand(updateCmd)
-}
-
-var updateCmd = &cobra.Command{
-	Use:   "update",
-	Short: "Sync community snips from GitHub",
-	Long:  `Download and update community-contributed snips from the official repository`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Updating community snips...")
-
-		communityDir := GetConfigDir() + "/snips/community"
-
-		count, err := repo.SyncCommunitySnips(communityDir)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error syncing snips: %v\n", err)
-			os.Exit(1)
-		}
-
-		fmt.Printf("✓ Successfully synced %d community snips\n", count)
-		fmt.Println("Run 'sniprun list' to see available snips")
-	},
-}
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mini-page/sniprun/internal/keyring"
+	"github.com/mini-page/sniprun/internal/pin"
+	"github.com/mini-page/sniprun/internal/repo"
+	"github.com/mini-page/sniprun/internal/snip"
+
+	"github.com/spf13/cobra"
+)
+
+var updateAllowUnsigned bool
+
+func init() {
+	updateCmd.Flags().BoolVar(&updateAllowUnsigned, "allow-unsigned", false, "Accept an archive that is not signed by a trusted key (its snips stay unverified)")
+	rootCmd.AddCommand(updateCmd)
+}
+
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Sync community snips from GitHub",
+	Long: `Download and update community-contributed snips from the official repository.
+
+The archive must carry a manifest signed by a key added with 'sniprun trust
+add-key'. Archives with a signature or file that does not match are always
+rejected; only snips covered by a valid signature are verified.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Updating community snips...")
+
+		communityDir := GetConfigDir() + "/snips/community"
+
+		keys, err := keyring.Load(GetConfigDir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		result, err := repo.SyncCommunitySnips(communityDir, repo.SyncOptions{
+			Keys:          keys,
+			AllowUnsigned: updateAllowUnsigned,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error syncing snips: %v\n", err)
+			if errors.Is(err, repo.ErrUnsigned) {
+				fmt.Fprintf(os.Stderr, "Use --allow-unsigned to install unverified snips anyway\n")
+			}
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Successfully synced %d community snips\n", result.Count)
+		if result.Signer != nil {
+			fmt.Printf("✓ %d verified, signed by '%s' (%s)\n", result.Verified, result.Signer.Name, result.Signer.Fingerprint())
+		} else {
+			fmt.Println("⚠️  The archive was not verified, its snips are untrusted community snips")
+		}
+		reportChangedPins()
+		reportStoredSecrets(communityDir)
+		fmt.Println("Run 'sniprun list' to see available snips")
+	},
+}
+
+// reportStoredSecrets warns about community snips that carry likely
+// secrets, which were published with them
+func reportStoredSecrets(communityDir string) {
+	entries, err := os.ReadDir(communityDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
+			continue
+		}
+		if s, err := snip.LoadSnip(filepath.Join(communityDir, entry.Name())); err == nil {
+			warnStoredSecrets(s)
+		}
+	}
+}
+
+// reportChangedPins lists community snips whose command changed since it was
+// approved. They ask for approval on their next run.
+func reportChangedPins() {
+	pins, err := pin.Load(GetConfigDir())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+
+	var changed []string
+	for name, p := range pins {
+		s, path, err := snip.FindSnip(GetConfigDir(), name)
+		if err != nil || !snip.IsCommunity(GetConfigDir(), path) {
+			continue
+		}
+		if !p.Matches(s.Command) {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	sort.Strings(changed)
+	fmt.Printf("⚠️  %d snip(s) you approved changed and will ask for approval on their next run:\n", len(changed))
+	for _, name := range changed {
+		fmt.Printf("  - %s\n", name)
+	}
+}
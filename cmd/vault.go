@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/snip"
+	"github.com/mini-page/sniprun/internal/vault"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	vaultUnlockTimeout time.Duration
+	vaultLockAt        string
+)
+
+func init() {
+	vaultUnlockCmd.Flags().DurationVar(&vaultUnlockTimeout, "timeout", 0, "How long to stay unlocked; default from vault_timeout in config (15m)")
+
+	vaultLockCmd.Flags().StringVar(&vaultLockAt, "at", "", "Wait and lock when the session started with this expiry ends")
+	vaultLockCmd.Flags().MarkHidden("at")
+
+	vaultCmd.AddCommand(vaultInitCmd, vaultUnlockCmd, vaultLockCmd, vaultStatusCmd, vaultAddCmd)
+	rootCmd.AddCommand(vaultCmd)
+}
+
+var vaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Keep sensitive snips and secrets in an encrypted vault",
+	Long: `The vault is a file in the config directory encrypted with AES-256-GCM under
+a key derived from a passphrase with Argon2id. It holds snips that should not
+sit in plain text in snips/local as well as secrets ('sniprun secret').
+
+Snips in the vault are listed and run like local snips while the vault is
+unlocked. Unlocking keeps the key in the user's runtime directory until the
+session times out or 'sniprun vault lock' is run.`,
+}
+
+var vaultInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create an empty vault",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if vault.Exists(GetConfigDir()) {
+			fmt.Fprintf(os.Stderr, "Error: a vault already exists at %s\n", vault.Path(GetConfigDir()))
+			os.Exit(1)
+		}
+
+		passphrase, err := readPassphrase(true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if _, err := vault.Create(GetConfigDir(), passphrase); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Created vault %s\n", vault.Path(GetConfigDir()))
+		fmt.Println("Unlock it with 'sniprun vault unlock', then move snips in with 'sniprun vault add <snip>'")
+	},
+}
+
+var vaultUnlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Open the vault for a while",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !vault.Exists(GetConfigDir()) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", vault.ErrNoVault)
+			os.Exit(1)
+		}
+
+		timeout := vaultUnlockTimeout
+		if timeout <= 0 {
+			timeout = GetConfig().VaultTimeout
+		}
+		if timeout <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: the timeout must be positive\n")
+			os.Exit(1)
+		}
+
+		passphrase, err := readPassphrase(false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		key, err := vault.Unlock(GetConfigDir(), passphrase)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		expires, err := vault.StartSession(GetConfigDir(), key, timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := startLockTimer(expires); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v; the key is removed on first use after it expires\n", err)
+		}
+		fmt.Printf("✓ Vault unlocked until %s\n", expires.Format("15:04:05"))
+	},
+}
+
+// startLockTimer starts a detached 'sniprun vault lock --at' that removes
+// the session key when it expires, even if sniprun is not run again
+func startLockTimer(expires time.Time) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to start the lock timer: %w", err)
+	}
+	c := exec.Command(exe, "--config", GetConfigDir(), "vault", "lock", "--at", expires.Format(time.RFC3339Nano))
+	vault.Detach(c)
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("failed to start the lock timer: %w", err)
+	}
+	return c.Process.Release()
+}
+
+var vaultLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Close the vault now",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if vaultLockAt != "" {
+			expires, err := time.Parse(time.RFC3339Nano, vaultLockAt)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --at time: %v\n", err)
+				os.Exit(1)
+			}
+			if err := vault.ExpireSession(GetConfigDir(), expires); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		if err := vault.Lock(GetConfigDir()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✓ Vault locked")
+	},
+}
+
+var vaultStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the vault is unlocked and what it holds",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !vault.Exists(GetConfigDir()) {
+			fmt.Println("No vault. Create one with 'sniprun vault init'.")
+			return
+		}
+
+		fmt.Printf("Vault: %s\n", vault.Path(GetConfigDir()))
+		key, expires, ok := vault.SessionKey(GetConfigDir())
+		if !ok {
+			fmt.Println("Status: locked")
+			return
+		}
+		fmt.Printf("Status: unlocked until %s\n", expires.Format("15:04:05"))
+
+		contents, err := vault.Load(GetConfigDir(), key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Snips: %d\n", len(contents.Snips))
+		fmt.Printf("Secrets: %d\n", len(contents.Secrets))
+	},
+}
+
+var vaultAddCmd = &cobra.Command{
+	Use:   "add [snip-name]",
+	Short: "Move a local snip into the vault",
+	Long: `Move a local snip into the vault and delete its plain text file. The vault
+has to be unlocked.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		s, path, err := snip.FindSnip(GetConfigDir(), args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if snip.IsVault(GetConfigDir(), path) {
+			fmt.Printf("Snip '%s' is already in the vault\n", s.Name)
+			return
+		}
+		if s.Trust != "local" {
+			fmt.Fprintf(os.Stderr, "Error: only local snips can be moved into the vault\n")
+			os.Exit(1)
+		}
+
+		// The vault encrypts the file, but the command is still shown and
+		// checked as written
+		if warnStoredSecrets(s) {
+			fmt.Fprintf(os.Stderr, "Use secret arguments or environment variables so they are masked when the snip runs\n")
+		}
+
+		if err := snip.SaveVaultSnip(GetConfigDir(), s); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.Remove(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: the snip is in the vault but its file could not be removed: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Moved '%s' into the vault\n", s.Name)
+	},
+}
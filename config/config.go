@@ -1,66 +1,161 @@
-package config
-
-import (
-	"fmt"
-	"os"
-	"path/filepath"
-
-	"gopkg.in/yaml.v3"
-)
-
-type Config struct {
-	GeminiAPIKey    string `yaml:"gemini_api_key"`
-	AutoUpdate      bool   `yaml:"auto_update"`
-	DefaultCategory string `yaml:"default_category"`
-	SkipSecurity    bool   `yaml:"skip_security"`
-}
-
-var DefaultConfig = Config{
-	GeminiAPIKey:    "",
-	AutoUpdate:      false,
-	DefaultCategory: "general",
-	SkipSecurity:    false,
-}
-
-// Load reads config from ~/.sniprun/config.yaml
-func Load(configDir string) (*Config, error) {
-	configPath := filepath.Join(configDir, "config.yaml")
-
-	// Return default if config doesn't exist
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return &DefaultConfig, nil
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config: %w", err)
-	}
-
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
-	}
-
-	// Override with environment variables
-	if apiKey := os.Getenv("GEMINI_API_KEY"); apiKey != "" {
-		cfg.GeminiAPIKey = apiKey
-	}
-
-	return &cfg, nil
-}
-
-// Save writes config to ~/.sniprun/config.yaml
-func Save(cfg *Config, configDir string) error {
-	configPath := filepath.Join(configDir, "config.yaml")
-
-	data, err := yaml.Marshal(cfg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
-	}
-
-	return nil
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	GeminiAPIKey    string `yaml:"gemini_api_key"`
+	AutoUpdate      bool   `yaml:"auto_update"`
+	DefaultCategory string `yaml:"default_category"`
+	SkipSecurity    bool   `yaml:"skip_security"`
+
+	// Output capture for 'sniprun run' (see 'sniprun logs')
+	CaptureOutput bool  `yaml:"capture_output"`
+	LogMaxBytes   int64 `yaml:"log_max_bytes"`
+	LogRetention  int   `yaml:"log_retention"`
+
+	// Validator selects the security check: llm, rules, command or composite
+	Validator string `yaml:"validator"`
+	// Composite lists the validators combined by the composite validator
+	Composite []string `yaml:"composite"`
+	// CompositeQuorum is how many validators must agree on a risk level,
+	// 0 means the highest risk wins
+	CompositeQuorum int `yaml:"composite_quorum"`
+	// ValidatorCommand is the program and arguments run by the command validator
+	ValidatorCommand []string `yaml:"validator_command"`
+
+	// CacheTTL is how long security verdicts are reused, 0 disables the cache
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+
+	// Sandbox configures sandboxed runs, see 'sniprun run --sandbox'
+	Sandbox SandboxConfig `yaml:"sandbox"`
+
+	// VaultTimeout is how long 'sniprun vault unlock' keeps the vault open
+	VaultTimeout time.Duration `yaml:"vault_timeout"`
+
+	// LLM configures the model used by the llm validator
+	LLM LLMConfig `yaml:"llm"`
+
+	// Profiles are named environments such as dev, staging and prod,
+	// selected with --profile or $SNIPRUN_PROFILE
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+	// DefaultProfile is used when no profile is selected
+	DefaultProfile string `yaml:"default_profile,omitempty"`
+}
+
+// Profile holds the variables and safeguards of an environment
+type Profile struct {
+	// Vars fill {{name}} placeholders of snips that are not arguments
+	Vars map[string]string `yaml:"vars,omitempty"`
+	// Color of the profile banner shown with every question: red, green,
+	// yellow, blue, magenta or cyan
+	Color string `yaml:"color,omitempty"`
+	// AlwaysConfirm asks before every run, whatever the security check finds
+	AlwaysConfirm bool `yaml:"always_confirm,omitempty"`
+	// ForbidCategories are snip categories that may not run
+	ForbidCategories []string `yaml:"forbid_categories,omitempty"`
+	// RequireSandbox runs every snip in the sandbox
+	RequireSandbox bool `yaml:"require_sandbox,omitempty"`
+}
+
+// SandboxConfig sets the restrictions of sandboxed runs on Linux
+type SandboxConfig struct {
+	AllowNetwork bool `yaml:"allow_network"`
+	// Strict refuses to run when the kernel lacks a restriction
+	Strict bool `yaml:"strict"`
+	// Resource limits, 0 leaves a limit unchanged
+	CPUSeconds uint64 `yaml:"cpu_seconds"`
+	FileSize   uint64 `yaml:"file_size"`
+	OpenFiles  uint64 `yaml:"open_files"`
+	Processes  uint64 `yaml:"processes"`
+}
+
+// LLMConfig selects a language model provider
+type LLMConfig struct {
+	// Provider is gemini, openai (any OpenAI-compatible endpoint) or ollama
+	Provider string `yaml:"provider"`
+	// BaseURL overrides the provider's default endpoint
+	BaseURL string `yaml:"base_url,omitempty"`
+	// Model defaults to gemini-2.5-flash, gpt-4o-mini or llama3. It has to
+	// support system instructions and structured output.
+	Model string `yaml:"model,omitempty"`
+	// APIKey is a key reference: env:NAME, file:PATH or the key itself
+	APIKey  string        `yaml:"api_key,omitempty"`
+	Timeout time.Duration `yaml:"timeout"`
+	// OnInvalidReply is the risk level assumed when the model's reply cannot
+	// be parsed or has an unknown risk level: dangerous (the default) or
+	// warning. Other values are rejected when the validator is loaded.
+	OnInvalidReply string `yaml:"on_invalid_reply"`
+}
+
+var DefaultConfig = Config{
+	GeminiAPIKey:    "",
+	AutoUpdate:      false,
+	DefaultCategory: "general",
+	SkipSecurity:    false,
+	CaptureOutput:   false,
+	LogMaxBytes:     1 << 20,
+	LogRetention:    200,
+	Validator:       "composite",
+	Composite:       []string{"rules", "llm"},
+	CacheTTL:        24 * time.Hour,
+	VaultTimeout:    15 * time.Minute,
+	Sandbox: SandboxConfig{
+		CPUSeconds: 600,
+		FileSize:   1 << 30,
+		OpenFiles:  1024,
+	},
+	LLM: LLMConfig{
+		Provider:       "gemini",
+		Timeout:        30 * time.Second,
+		OnInvalidReply: "dangerous",
+	},
+}
+
+// Load reads config from ~/.sniprun/config.yaml
+func Load(configDir string) (*Config, error) {
+	configPath := filepath.Join(configDir, "config.yaml")
+
+	cfg := DefaultConfig
+
+	// Use defaults if config doesn't exist
+	if _, err := os.Stat(configPath); err == nil {
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	// Override with environment variables
+	if apiKey := os.Getenv("GEMINI_API_KEY"); apiKey != "" {
+		cfg.GeminiAPIKey = apiKey
+	}
+
+	return &cfg, nil
+}
+
+// Save writes config to ~/.sniprun/config.yaml
+func Save(cfg *Config, configDir string) error {
+	configPath := filepath.Join(configDir, "config.yaml")
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	return nil
 }
\ No newline at end of file
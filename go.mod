@@ -4,7 +4,11 @@ go 1.21
 
 require (
 	github.com/spf13/cobra v1.10.1
+	golang.org/x/crypto v0.17.0
+	golang.org/x/sys v0.15.0
+	golang.org/x/term v0.15.0
 	gopkg.in/yaml.v3 v3.0.1
+	mvdan.cc/sh/v3 v3.7.0
 )
 
 require (
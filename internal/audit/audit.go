@@ -0,0 +1,211 @@
+package audit
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/secrets"
+	"github.com/mini-page/sniprun/internal/security"
+	"github.com/mini-page/sniprun/internal/snip"
+)
+
+// Item is a snip to audit
+type Item struct {
+	Snip *snip.Snip
+	// Path is the file the snip was loaded from
+	Path string
+	// Command is the snip's command with example argument values
+	Command string
+}
+
+// Entry is the audit outcome of one snip
+type Entry struct {
+	Snip      string             `json:"snip"`
+	Category  string             `json:"category,omitempty"`
+	Trust     string             `json:"trust,omitempty"`
+	Path      string             `json:"path"`
+	Line      int                `json:"line,omitempty"`
+	Command   string             `json:"command"`
+	RiskLevel security.RiskLevel `json:"risk_level,omitempty"`
+	Reason    string             `json:"reason,omitempty"`
+	Verdicts  []security.Verdict `json:"verdicts,omitempty"`
+	Findings  []security.Finding `json:"findings,omitempty"`
+	Error     string             `json:"error,omitempty"`
+}
+
+// RuleStoredSecret is the finding for a likely secret in a snip file
+const RuleStoredSecret = "stored-secret"
+
+// storedValidator names the verdict on stored secrets
+const storedValidator = "audit"
+
+// CheckFunc validates the command of a snip
+type CheckFunc func(s *snip.Snip, command string) (*security.ValidationResult, error)
+
+// Options control how an audit runs
+type Options struct {
+	// Workers is the number of snips validated at the same time
+	Workers int
+	// Interval is the minimum time between two validations, to stay within
+	// the rate limits of remote validators. 0 means no limit.
+	Interval time.Duration
+	Check    CheckFunc
+	// Progress is called after each snip, if set
+	Progress func(done, total int)
+}
+
+// Run validates all items and returns the entries ordered by risk, most
+// severe first, then by snip name
+func Run(items []*Item, opts Options) []*Entry {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var tick <-chan time.Time
+	if opts.Interval > 0 {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	jobs := make(chan int)
+	entries := make([]*Entry, len(items))
+
+	var mu sync.Mutex
+	done := 0
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entries[i] = check(items[i], opts.Check)
+
+				if opts.Progress != nil {
+					mu.Lock()
+					done++
+					opts.Progress(done, len(items))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range items {
+		if tick != nil && i > 0 {
+			<-tick
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].severity() != entries[j].severity() {
+			return entries[i].severity() > entries[j].severity()
+		}
+		return entries[i].Snip < entries[j].Snip
+	})
+	return entries
+}
+
+func check(item *Item, checkFn CheckFunc) *Entry {
+	s := item.Snip
+
+	// Stored secrets are reported, but neither printed nor sent to
+	// remote validators
+	var stored []security.Finding
+	var values []string
+	for _, f := range s.StoredSecrets() {
+		stored = append(stored, security.Finding{
+			RuleID:  RuleStoredSecret,
+			Level:   security.RiskWarning,
+			Message: fmt.Sprintf("%s contains a %s (%s) in plain text", f.Field, f.Kind, f.Masked()),
+		})
+		values = append(values, f.Value)
+	}
+	command := secrets.Redact(item.Command, values)
+
+	entry := &Entry{
+		Snip:     s.Name,
+		Category: s.Category,
+		Trust:    s.Trust,
+		Path:     item.Path,
+		Line:     commandLine(item.Path),
+		Command:  command,
+		Findings: stored,
+	}
+
+	result, err := checkFn(s, command)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+
+	entry.RiskLevel = result.RiskLevel
+	entry.Reason = result.Reason
+	entry.Verdicts = result.Verdicts
+	entry.Findings = append(result.Findings, stored...)
+	if len(stored) > 0 {
+		reason := fmt.Sprintf("%d secret(s) stored in plain text", len(stored))
+		entry.Verdicts = append(result.Contributions(), security.Verdict{Validator: storedValidator, RiskLevel: security.RiskWarning, Reason: reason})
+		if entry.RiskLevel.Severity() < security.RiskWarning.Severity() {
+			entry.RiskLevel = security.RiskWarning
+			entry.Reason = reason
+		}
+	}
+	return entry
+}
+
+// checked is the risk level the validators found, which is safe when only
+// stored secrets raised the entry to a warning
+func (e *Entry) checked() security.RiskLevel {
+	if n := len(e.Verdicts); n > 0 && e.Verdicts[n-1].Validator == storedValidator && e.Reason == e.Verdicts[n-1].Reason {
+		return security.RiskSafe
+	}
+	return e.RiskLevel
+}
+
+// severity orders entries, failed checks rank between dangerous and warning
+func (e *Entry) severity() int {
+	if e.Error != "" {
+		return 3
+	}
+	return e.RiskLevel.Severity() * 2
+}
+
+// Failed reports whether any entry could not be checked or reached the
+// given risk level
+func Failed(entries []*Entry, level security.RiskLevel) bool {
+	for _, e := range entries {
+		if e.Error != "" || e.RiskLevel.Severity() >= level.Severity() {
+			return true
+		}
+	}
+	return false
+}
+
+// commandLine finds the line of the command key in a snip file, so reports
+// can point at it. It returns 0 if it cannot be found.
+func commandLine(path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.HasPrefix(strings.TrimSpace(scanner.Text()), "command:") {
+			return line
+		}
+	}
+	return 0
+}
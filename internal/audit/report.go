@@ -0,0 +1,246 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mini-page/sniprun/internal/security"
+)
+
+// groups are the sections of a text report, in order
+var groups = []struct {
+	title string
+	match func(e *Entry) bool
+}{
+	{"DANGEROUS", func(e *Entry) bool { return e.Error == "" && e.RiskLevel == security.RiskDangerous }},
+	{"CHECK FAILED", func(e *Entry) bool { return e.Error != "" }},
+	{"WARNING", func(e *Entry) bool { return e.Error == "" && e.RiskLevel == security.RiskWarning }},
+	{"SAFE", func(e *Entry) bool { return e.Error == "" && e.RiskLevel == security.RiskSafe }},
+}
+
+// WriteText writes a report grouped by risk level. Safe snips are only
+// listed by name.
+func WriteText(w io.Writer, entries []*Entry) {
+	var summary []string
+
+	for _, g := range groups {
+		var matched []*Entry
+		for _, e := range entries {
+			if g.match(e) {
+				matched = append(matched, e)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		summary = append(summary, fmt.Sprintf("%d %s", len(matched), strings.ToLower(g.title)))
+
+		fmt.Fprintf(w, "%s (%d)\n", g.title, len(matched))
+
+		if g.title == "SAFE" {
+			names := make([]string, len(matched))
+			for i, e := range matched {
+				names[i] = e.Snip
+			}
+			fmt.Fprintf(w, "  %s\n\n", strings.Join(names, ", "))
+			continue
+		}
+
+		for _, e := range matched {
+			fmt.Fprintf(w, "  %s [%s] %s\n", e.Snip, orUnknown(e.Trust), e.Path)
+			fmt.Fprintf(w, "    $ %s\n", e.Command)
+			if e.Error != "" {
+				fmt.Fprintf(w, "    - %s\n", e.Error)
+				continue
+			}
+
+			result := &security.ValidationResult{RiskLevel: e.RiskLevel, Reason: e.Reason, Verdicts: e.Verdicts}
+			for _, line := range result.Explanation() {
+				fmt.Fprintf(w, "    - %s\n", line)
+			}
+			for _, f := range e.Findings {
+				if f.RuleID == RuleStoredSecret {
+					fmt.Fprintf(w, "    - %s\n", f)
+				}
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "Audited %d snips: %s\n", len(entries), strings.Join(summary, ", "))
+}
+
+// WriteJSON writes all entries with a count per risk level
+func WriteJSON(w io.Writer, entries []*Entry) error {
+	counts := map[string]int{}
+	for _, e := range entries {
+		if e.Error != "" {
+			counts["failed"]++
+		} else {
+			counts[string(e.RiskLevel)]++
+		}
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Summary map[string]int `json:"summary"`
+		Snips   []*Entry       `json:"snips"`
+	}{counts, entries}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// SARIF 2.1.0 types, limited to what code scanning tools read
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region *sarifRegion `json:"region,omitempty"`
+	} `json:"physicalLocation"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+var sarifLevels = map[security.RiskLevel]string{
+	security.RiskDangerous: "error",
+	security.RiskWarning:   "warning",
+}
+
+// WriteSARIF writes risky snips and failed checks as SARIF results. Each
+// analyzer finding becomes its own result; verdicts without findings are
+// reported under a rule per risk level.
+func WriteSARIF(w io.Writer, entries []*Entry, baseDir string) error {
+	rules := map[string]string{}
+	var results []sarifResult
+
+	add := func(e *Entry, ruleID, level, message string) {
+		if _, ok := rules[ruleID]; !ok {
+			rules[ruleID] = message
+		}
+
+		loc := sarifLocation{}
+		loc.PhysicalLocation.ArtifactLocation.URI = artifactURI(e.Path, baseDir)
+		if e.Line > 0 {
+			loc.PhysicalLocation.Region = &sarifRegion{StartLine: e.Line}
+		}
+
+		results = append(results, sarifResult{
+			RuleID:    ruleID,
+			Level:     level,
+			Message:   sarifMessage{Text: fmt.Sprintf("%s: %s", e.Snip, message)},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	for _, e := range entries {
+		var findings []security.Finding
+		for _, f := range e.Findings {
+			if f.RuleID == RuleStoredSecret {
+				add(e, f.RuleID, sarifLevels[f.Level], f.Message)
+			} else {
+				findings = append(findings, f)
+			}
+		}
+
+		switch {
+		case e.Error != "":
+			add(e, "check-failed", "warning", "Security check failed: "+e.Error)
+		case e.checked().Severity() > 0 && len(findings) > 0:
+			for _, f := range findings {
+				add(e, f.RuleID, sarifLevels[f.Level], f.Message)
+			}
+		case e.checked().Severity() > 0:
+			add(e, "risk-"+string(e.RiskLevel), sarifLevels[e.RiskLevel], e.Reason)
+		}
+	}
+
+	ids := make([]string, 0, len(rules))
+	for id := range rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	driver := sarifDriver{Name: "sniprun", InformationURI: "https://github.com/mini-page/sniprun", Rules: []sarifRule{}}
+	for _, id := range ids {
+		driver.Rules = append(driver.Rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: rules[id]}})
+	}
+
+	if results == nil {
+		results = []sarifResult{}
+	}
+
+	data, err := json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// artifactURI makes paths relative to baseDir where possible, as code
+// scanning expects paths within the repository
+func artifactURI(path, baseDir string) string {
+	if baseDir != "" {
+		if rel, err := filepath.Rel(baseDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,269 @@
+package auditlog
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/filelock"
+	"github.com/mini-page/sniprun/internal/security"
+)
+
+// Events recorded in the audit log
+const (
+	EventRun        = "run"
+	EventSource     = "source"
+	EventBench      = "bench"
+	EventBlocked    = "blocked"
+	EventApproved   = "approved"
+	EventDeclined   = "declined"
+	EventKeyAdded   = "key-added"
+	EventKeyRemoved = "key-removed"
+)
+
+// Record is one entry of the audit log. Each record carries the hash of
+// the one before it, so editing or deleting a record breaks the chain.
+type Record struct {
+	Seq  int       `json:"seq"`
+	Time time.Time `json:"time"`
+	User string    `json:"user"`
+	Host string    `json:"host"`
+
+	Event string `json:"event"`
+	Snip  string `json:"snip,omitempty"`
+	Trust string `json:"trust,omitempty"`
+	// Profile is the profile the snip was run with
+	Profile string `json:"profile,omitempty"`
+	// CommandHash is the SHA-256 of the final command, arguments included
+	// and secret values masked, so the hash cannot be used to guess them
+	CommandHash string             `json:"command_hash,omitempty"`
+	RiskLevel   security.RiskLevel `json:"risk_level,omitempty"`
+	Verdicts    []security.Verdict `json:"verdicts,omitempty"`
+	// Overrides are the safety measures the user turned off, e.g. skip-check
+	Overrides []string `json:"overrides,omitempty"`
+	ExitCode  *int     `json:"exit_code,omitempty"`
+	Schedule  string   `json:"schedule,omitempty"`
+	Detail    string   `json:"detail,omitempty"`
+
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+const (
+	logFile  = "audit-log.jsonl"
+	headFile = "audit-log.head"
+	lockFile = "audit-log.lock"
+)
+
+// Path returns the location of the audit log
+func Path(configDir string) string {
+	return filepath.Join(configDir, logFile)
+}
+
+// HashCommand returns the hash a command is recorded by
+func HashCommand(command string) string {
+	sum := sha256.Sum256([]byte(command))
+	return hex.EncodeToString(sum[:])
+}
+
+// computeHash hashes a record with its Hash field empty
+func (r *Record) computeHash() string {
+	c := *r
+	c.Hash = ""
+	data, _ := json.Marshal(&c)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+var mu sync.Mutex
+
+// readHead returns the sequence number and hash of the last record. A
+// missing head is only valid while the log is empty.
+func readHead(configDir string) (int, string, error) {
+	data, err := os.ReadFile(filepath.Join(configDir, headFile))
+	if os.IsNotExist(err) {
+		if info, err := os.Stat(Path(configDir)); err == nil && info.Size() > 0 {
+			return 0, "", fmt.Errorf("audit log head is missing; run 'sniprun audit-log verify'")
+		}
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read audit log head: %w", err)
+	}
+
+	var seq int
+	var hash string
+	if _, err := fmt.Sscanf(string(data), "%d %s", &seq, &hash); err != nil || seq < 1 {
+		return 0, "", fmt.Errorf("audit log head is corrupt; run 'sniprun audit-log verify'")
+	}
+	return seq, hash, nil
+}
+
+// writeHead replaces the head file in one step
+func writeHead(configDir string, seq int, hash string) error {
+	tmp, err := os.CreateTemp(configDir, headFile+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write audit log head: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := fmt.Fprintf(tmp, "%d %s\n", seq, hash); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write audit log head: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write audit log head: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(configDir, headFile)); err != nil {
+		return fmt.Errorf("failed to write audit log head: %w", err)
+	}
+	return nil
+}
+
+// Check reports whether records can be appended, so callers that must not
+// act without an audit record can find out before they act
+func Check(configDir string) error {
+	if _, _, err := readHead(configDir); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(Path(configDir), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return f.Close()
+}
+
+// Append fills in the sequence number, time, user, host and hashes of r and
+// adds it to the end of the log. The chain continues from the head file, so
+// the log itself is not read; Verify checks that the two agree.
+func Append(configDir string, r *Record) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	unlock, err := filelock.Acquire(filepath.Join(configDir, lockFile), "audit log")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	seq, hash, err := readHead(configDir)
+	if err != nil {
+		return err
+	}
+	r.Seq = seq + 1
+	r.PrevHash = hash
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+	r.User = currentUser()
+	r.Host, _ = os.Hostname()
+	r.Hash = r.computeHash()
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	f, err := os.OpenFile(Path(configDir), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	// The head detects records cut off the end of the log
+	return writeHead(configDir, r.Seq, r.Hash)
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	for _, name := range []string{"USER", "USERNAME", "LOGNAME"} {
+		if u := os.Getenv(name); u != "" {
+			return u
+		}
+	}
+	return fmt.Sprintf("uid:%d", os.Getuid())
+}
+
+// Load reads all records, oldest first. Unlike the history, a line that
+// does not parse is an error, since it means the log was tampered with.
+func Load(configDir string) ([]*Record, error) {
+	f, err := os.Open(Path(configDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer f.Close()
+
+	var records []*Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("audit log line %d is corrupt: %w", line, err)
+		}
+		records = append(records, &r)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return records, nil
+}
+
+// ErrTampered is wrapped by every error Verify reports about the chain
+var ErrTampered = errors.New("audit log has been tampered with")
+
+// Verify checks that every record hashes to its stored hash, links to the
+// record before it and that the last record matches the recorded head
+func Verify(configDir string) (int, error) {
+	records, err := Load(configDir)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrTampered, err)
+	}
+
+	prev := ""
+	for i, r := range records {
+		if r.Seq != i+1 {
+			return i, fmt.Errorf("%w: expected record %d, found %d", ErrTampered, i+1, r.Seq)
+		}
+		if r.PrevHash != prev {
+			return i, fmt.Errorf("%w: record %d does not follow record %d", ErrTampered, r.Seq, r.Seq-1)
+		}
+		if r.computeHash() != r.Hash {
+			return i, fmt.Errorf("%w: record %d was modified", ErrTampered, r.Seq)
+		}
+		prev = r.Hash
+	}
+
+	data, err := os.ReadFile(filepath.Join(configDir, headFile))
+	if os.IsNotExist(err) && len(records) == 0 {
+		return 0, nil
+	}
+	if err != nil {
+		return len(records), fmt.Errorf("%w: head is missing: %v", ErrTampered, err)
+	}
+	head := fmt.Sprintf("%d %s", len(records), prev)
+	if strings.TrimSpace(string(data)) != head {
+		return len(records), fmt.Errorf("%w: log ends at record %d, head says %s", ErrTampered, len(records), strings.TrimSpace(string(data)))
+	}
+
+	return len(records), nil
+}
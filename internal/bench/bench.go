@@ -0,0 +1,76 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/sandbox"
+	"github.com/mini-page/sniprun/internal/snip"
+)
+
+// Options controls how a benchmark is run
+type Options struct {
+	Runs          int
+	Warmup        int
+	IgnoreFailure bool
+
+	// Progress, if set, is called after every timed run
+	Progress func(done, total int)
+
+	// Sandbox, if set, runs every iteration in the sandbox
+	Sandbox *sandbox.Config
+
+	// Env holds NAME=value pairs added to the environment of every iteration
+	Env []string
+}
+
+// Result is the outcome of benchmarking one snip invocation
+type Result struct {
+	Name string `json:"name"`
+	// Command has the values of secret arguments masked, since results
+	// are exported
+	Command  string          `json:"command"`
+	Times    []time.Duration `json:"times"`
+	Failures int             `json:"failures"`
+	Stats    Stats           `json:"stats"`
+}
+
+// Run executes command repeatedly and records the wall-clock time of each
+// run. Output is discarded, as timing the terminal is not the point. shown
+// is the command with secrets masked, which is what the result keeps.
+func Run(s *snip.Snip, name, command, shown string, opts Options) (*Result, error) {
+	if opts.Runs <= 0 {
+		return nil, fmt.Errorf("number of runs must be positive")
+	}
+
+	quiet := snip.ExecOptions{Stdout: io.Discard, Stderr: io.Discard, Sandbox: opts.Sandbox, Env: opts.Env}
+
+	for i := 0; i < opts.Warmup; i++ {
+		if err := s.Run(command, quiet); err != nil && !opts.IgnoreFailure {
+			return nil, fmt.Errorf("warmup run of %s failed: %w (use --ignore-failure to continue)", name, err)
+		}
+	}
+
+	result := &Result{Name: name, Command: shown}
+	for i := 0; i < opts.Runs; i++ {
+		started := time.Now()
+		err := s.Run(command, quiet)
+		elapsed := time.Since(started)
+
+		if err != nil {
+			if !opts.IgnoreFailure {
+				return nil, fmt.Errorf("run %d of %s failed: %w (use --ignore-failure to continue)", i+1, name, err)
+			}
+			result.Failures++
+		}
+
+		result.Times = append(result.Times, elapsed)
+		if opts.Progress != nil {
+			opts.Progress(i+1, opts.Runs)
+		}
+	}
+
+	result.Stats = Compute(result.Times)
+	return result, nil
+}
@@ -0,0 +1,64 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ExportJSON writes the results with all individual timings to path
+func ExportJSON(results []*Result, path string) error {
+	data, err := json.MarshalIndent(struct {
+		Results []*Result `json:"results"`
+	}{results}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal results: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// ExportMarkdown writes the results as a Markdown table to path. Relative
+// speed is given against the fastest result.
+func ExportMarkdown(results []*Result, path string) error {
+	var b strings.Builder
+	b.WriteString("| Command | Mean [ms] | Median [ms] | Min [ms] | Max [ms] | Relative |\n")
+	b.WriteString("|:---|---:|---:|---:|---:|---:|\n")
+
+	fastest := Fastest(results)
+	for _, r := range results {
+		ratio, dev := Ratio(fastest.Stats, r.Stats)
+		fmt.Fprintf(&b, "| `%s` | %.1f ± %.1f | %.1f | %.1f | %.1f | %.2f ± %.2f |\n",
+			strings.ReplaceAll(r.Name, "|", "\\|"),
+			ms(r.Stats.Mean), ms(r.Stats.StdDev),
+			ms(r.Stats.Median),
+			ms(r.Stats.Min), ms(r.Stats.Max),
+			ratio, dev,
+		)
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Fastest returns the result with the lowest mean time
+func Fastest(results []*Result) *Result {
+	var fastest *Result
+	for _, r := range results {
+		if fastest == nil || r.Stats.Mean < fastest.Stats.Mean {
+			fastest = r
+		}
+	}
+	return fastest
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
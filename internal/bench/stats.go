@@ -0,0 +1,84 @@
+package bench
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Stats summarises the timings of a benchmarked command
+type Stats struct {
+	Mean     time.Duration `json:"mean"`
+	Median   time.Duration `json:"median"`
+	StdDev   time.Duration `json:"stddev"`
+	Min      time.Duration `json:"min"`
+	Max      time.Duration `json:"max"`
+	Outliers int           `json:"outliers"`
+}
+
+// Compute summarises a set of timings. Outliers are runs more than 1.5
+// interquartile ranges outside the first or third quartile.
+func Compute(times []time.Duration) Stats {
+	if len(times) == 0 {
+		return Stats{}
+	}
+
+	sorted := append([]time.Duration(nil), times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum float64
+	for _, t := range sorted {
+		sum += float64(t)
+	}
+	mean := sum / float64(len(sorted))
+
+	var variance float64
+	if len(sorted) > 1 {
+		for _, t := range sorted {
+			d := float64(t) - mean
+			variance += d * d
+		}
+		variance /= float64(len(sorted) - 1)
+	}
+
+	q1 := quantile(sorted, 0.25)
+	q3 := quantile(sorted, 0.75)
+	iqr := q3 - q1
+	outliers := 0
+	for _, t := range sorted {
+		if float64(t) < q1-1.5*iqr || float64(t) > q3+1.5*iqr {
+			outliers++
+		}
+	}
+
+	return Stats{
+		Mean:     time.Duration(mean),
+		Median:   time.Duration(quantile(sorted, 0.5)),
+		StdDev:   time.Duration(math.Sqrt(variance)),
+		Min:      sorted[0],
+		Max:      sorted[len(sorted)-1],
+		Outliers: outliers,
+	}
+}
+
+// quantile interpolates linearly between the closest ranks of sorted
+func quantile(sorted []time.Duration, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	frac := pos - float64(lo)
+	return float64(sorted[lo])*(1-frac) + float64(sorted[hi])*frac
+}
+
+// Ratio returns how many times slower b is than a, with the propagated
+// standard deviation of that ratio
+func Ratio(a, b Stats) (float64, float64) {
+	if a.Mean == 0 || b.Mean == 0 {
+		return 0, 0
+	}
+
+	ratio := float64(b.Mean) / float64(a.Mean)
+	relA := float64(a.StdDev) / float64(a.Mean)
+	relB := float64(b.StdDev) / float64(b.Mean)
+	return ratio, ratio * math.Sqrt(relA*relA+relB*relB)
+}
@@ -0,0 +1,44 @@
+package filelock
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// timeout is how long Acquire waits for another process
+	timeout = 5 * time.Second
+	// stale is the age after which a lock is assumed to be left behind by a
+	// crashed process
+	stale = 30 * time.Second
+)
+
+// Acquire serializes access to a file shared by several sniprun processes,
+// such as the daemon and an interactive run, by creating a lock file at
+// path. name describes the locked file in errors. The returned function
+// releases the lock.
+func Acquire(path, name string) (func(), error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock %s: %w", name, err)
+		}
+
+		// A lock left behind by a crashed process
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > stale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another process (%s)", name, path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,85 @@
+package history
+
+import (
+	"time"
+)
+
+// Stats summarises the recorded runs of one snip
+type Stats struct {
+	Snip          string
+	Runs          int
+	Failures      int
+	TotalDuration time.Duration
+	LastRun       time.Time
+	Frecency      float64
+}
+
+// FailureRate returns the fraction of runs that exited with an error
+func (s *Stats) FailureRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Failures) / float64(s.Runs)
+}
+
+// AverageDuration returns the mean duration of a run
+func (s *Stats) AverageDuration() time.Duration {
+	if s.Runs == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Runs)
+}
+
+// recencyWeight scores a single run by its age, so that a snip run a few
+// times today outranks one run many times last year
+func recencyWeight(age time.Duration) float64 {
+	switch {
+	case age < 4*time.Hour:
+		return 100
+	case age < 24*time.Hour:
+		return 80
+	case age < 7*24*time.Hour:
+		return 60
+	case age < 30*24*time.Hour:
+		return 40
+	case age < 90*24*time.Hour:
+		return 20
+	default:
+		return 10
+	}
+}
+
+// Summarize computes per-snip statistics, including the frecency score,
+// as of the given time
+func Summarize(entries []*Entry, now time.Time) map[string]*Stats {
+	stats := make(map[string]*Stats)
+
+	for _, e := range entries {
+		st, ok := stats[e.Snip]
+		if !ok {
+			st = &Stats{Snip: e.Snip}
+			stats[e.Snip] = st
+		}
+
+		st.Runs++
+		if e.ExitCode != 0 {
+			st.Failures++
+		}
+		st.TotalDuration += e.Duration
+		if e.Timestamp.After(st.LastRun) {
+			st.LastRun = e.Timestamp
+		}
+		st.Frecency += recencyWeight(now.Sub(e.Timestamp))
+	}
+
+	return stats
+}
+
+// Frecency returns the frecency score of every snip that has been run
+func Frecency(entries []*Entry, now time.Time) map[string]float64 {
+	scores := make(map[string]float64)
+	for name, st := range Summarize(entries, now) {
+		scores[name] = st.Frecency
+	}
+	return scores
+}
@@ -0,0 +1,210 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/filelock"
+	"github.com/mini-page/sniprun/internal/snip"
+)
+
+// Entry is one recorded snip invocation
+type Entry struct {
+	ID        int           `json:"id"`
+	Snip      string        `json:"snip"`
+	Args      []string      `json:"args,omitempty"`
+	Cwd       string        `json:"cwd"`
+	ExitCode  int           `json:"exit_code"`
+	Duration  time.Duration `json:"duration"`
+	Timestamp time.Time     `json:"timestamp"`
+
+	// Log is the file name of the captured output, see 'sniprun logs'
+	Log string `json:"log,omitempty"`
+
+	// Schedule is the ID of the scheduled job that started the run, if any
+	Schedule string `json:"schedule,omitempty"`
+
+	// SecretArgs lists the positions of arguments whose values were withheld.
+	// Their slot in Args is left empty and must be asked for again on re-run.
+	SecretArgs []int `json:"secret_args,omitempty"`
+}
+
+// historyFile is the append-only store, relative to the config dir
+const historyFile = "history.jsonl"
+
+// lockFile serializes appends, relative to the config dir
+const lockFile = "history.lock"
+
+// Path returns the location of the history file
+func Path(configDir string) string {
+	return filepath.Join(configDir, historyFile)
+}
+
+// Load reads all recorded invocations, oldest first
+func Load(configDir string) ([]*Entry, error) {
+	f, err := os.Open(Path(configDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer f.Close()
+
+	var entries []*Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// A torn write should not make the whole history unreadable
+			continue
+		}
+		entries = append(entries, &e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Append assigns the next ID to e and adds it to the end of the history.
+// The history is locked meanwhile, so processes running at the same time
+// do not hand out the same ID.
+func Append(configDir string, e *Entry) error {
+	unlock, err := filelock.Acquire(filepath.Join(configDir, lockFile), "history")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := Load(configDir)
+	if err != nil {
+		return err
+	}
+
+	e.ID = 1
+	if len(entries) > 0 {
+		e.ID = entries[len(entries)-1].ID + 1
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+
+	f, err := os.OpenFile(Path(configDir), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+
+	return nil
+}
+
+// NewEntry describes an invocation of s that started at the given time and
+// ended with runErr. Values of secret arguments are left out.
+func NewEntry(s *snip.Snip, args []string, cwd string, started time.Time, runErr error) *Entry {
+	e := &Entry{
+		Snip:      s.Name,
+		Cwd:       cwd,
+		ExitCode:  ExitCode(runErr),
+		Duration:  time.Since(started),
+		Timestamp: started,
+	}
+
+	e.Args = make([]string, len(args))
+	for i, value := range args {
+		if i < len(s.Args) && s.Args[i].Secret {
+			e.SecretArgs = append(e.SecretArgs, i)
+			continue
+		}
+		e.Args[i] = value
+	}
+
+	return e
+}
+
+// Find returns the entry with the given ID
+func Find(entries []*Entry, id int) (*Entry, error) {
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("no history entry #%d", id)
+}
+
+// Last returns the most recent entry, optionally restricted to one snip
+func Last(entries []*Entry, snipName string) (*Entry, error) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if snipName == "" || entries[i].Snip == snipName {
+			return entries[i], nil
+		}
+	}
+
+	if snipName != "" {
+		return nil, fmt.Errorf("no previous run of '%s'", snipName)
+	}
+	return nil, fmt.Errorf("history is empty")
+}
+
+// ArgValues returns previously used values for one argument position of a
+// snip, most recent first and without duplicates
+func ArgValues(entries []*Entry, snipName string, position int) []string {
+	var values []string
+	seen := make(map[string]bool)
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Snip != snipName || position >= len(e.Args) || e.isSecret(position) {
+			continue
+		}
+
+		v := e.Args[position]
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+func (e *Entry) isSecret(position int) bool {
+	for _, p := range e.SecretArgs {
+		if p == position {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitCode extracts the process exit status from the error returned by a run
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
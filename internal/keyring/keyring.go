@@ -0,0 +1,93 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Key is an ed25519 public key trusted to sign community snip manifests
+type Key struct {
+	Name      string    `yaml:"name"`
+	PublicKey string    `yaml:"public_key"` // base64
+	Added     time.Time `yaml:"added"`
+}
+
+// keysFile is where trusted keys are stored, relative to the config dir
+const keysFile = "trusted_keys.yaml"
+
+// ParseKey decodes a base64 ed25519 public key
+func ParseKey(encoded string) (ed25519.PublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("public key is not valid base64: %w", err)
+	}
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(data))
+	}
+	return ed25519.PublicKey(data), nil
+}
+
+// Fingerprint is a short identifier of a key for display
+func (k *Key) Fingerprint() string {
+	key, err := ParseKey(k.PublicKey)
+	if err != nil {
+		return "invalid"
+	}
+	return fmt.Sprintf("%x", key[:8])
+}
+
+// Verify reports whether sig is a valid signature of message by the key
+func (k *Key) Verify(message, sig []byte) bool {
+	key, err := ParseKey(k.PublicKey)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key, message, sig)
+}
+
+// Load reads the trusted keys from the config dir
+func Load(configDir string) ([]*Key, error) {
+	data, err := os.ReadFile(filepath.Join(configDir, keysFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted keys: %w", err)
+	}
+
+	var keys []*Key
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse trusted keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Save writes the trusted keys to the config dir
+func Save(configDir string, keys []*Key) error {
+	data, err := yaml.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trusted keys: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(configDir, keysFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write trusted keys: %w", err)
+	}
+	return nil
+}
+
+// Find returns the key with the given name
+func Find(keys []*Key, name string) (*Key, error) {
+	for _, k := range keys {
+		if k.Name == name {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("key '%s' not found", name)
+}
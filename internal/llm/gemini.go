@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Gemini talks to the Google Gemini generateContent API
+type Gemini struct {
+	BaseURL string
+	Model   string
+	APIKey  string
+	Client  *http.Client
+}
+
+func (g *Gemini) Name() string {
+	return "gemini/" + g.Model
+}
+
+// Endpoint is the API base URL the requests go to
+func (g *Gemini) Endpoint() string {
+	return g.BaseURL
+}
+
+func (g *Gemini) Complete(ctx context.Context, prompt *Prompt) (string, error) {
+	generationConfig := map[string]interface{}{
+		"temperature":     0.1,
+		"maxOutputTokens": 1024,
+	}
+	// Thinking tokens count against the output limit and a verdict needs
+	// none. 2.5 Flash models can turn thinking off, Pro models cannot.
+	if strings.HasPrefix(g.Model, "gemini-2.5-flash") {
+		generationConfig["thinkingConfig"] = map[string]interface{}{"thinkingBudget": 0}
+	}
+	if prompt.Schema != nil {
+		generationConfig["responseMimeType"] = "application/json"
+		generationConfig["responseSchema"] = geminiSchema(prompt.Schema)
+	}
+
+	body := map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{
+				"role": "user",
+				"parts": []map[string]interface{}{
+					{"text": prompt.User},
+				},
+			},
+		},
+		"generationConfig": generationConfig,
+	}
+	if prompt.System != "" {
+		body["systemInstruction"] = map[string]interface{}{
+			"parts": []map[string]interface{}{
+				{"text": prompt.System},
+			},
+		}
+	}
+
+	var resp struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"content"`
+			FinishReason string `json:"finishReason"`
+		} `json:"candidates"`
+	}
+
+	url := fmt.Sprintf("%s/models/%s:generateContent", g.BaseURL, g.Model)
+	if err := postJSON(ctx, g.Client, url, map[string]string{"x-goog-api-key": g.APIKey}, body, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Candidates) == 0 {
+		return "", fmt.Errorf("%w: empty response from API", ErrInvalidReply)
+	}
+	candidate := resp.Candidates[0]
+	if candidate.FinishReason == "MAX_TOKENS" {
+		return "", fmt.Errorf("%w: reply cut off at the token limit", ErrInvalidReply)
+	}
+
+	var text strings.Builder
+	for _, part := range candidate.Content.Parts {
+		text.WriteString(part.Text)
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("%w: empty response from API (finish reason %s)", ErrInvalidReply, candidate.FinishReason)
+	}
+	return text.String(), nil
+}
+
+// geminiSchema converts a JSON schema into the OpenAPI subset Gemini
+// accepts, which spells types in upper case and has no additionalProperties
+func geminiSchema(schema map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(schema))
+	for k, v := range schema {
+		switch k {
+		case "additionalProperties":
+			continue
+		case "type":
+			if t, ok := v.(string); ok {
+				v = strings.ToUpper(t)
+			}
+		case "properties":
+			if props, ok := v.(map[string]interface{}); ok {
+				converted := make(map[string]interface{}, len(props))
+				for name, prop := range props {
+					if p, ok := prop.(map[string]interface{}); ok {
+						prop = geminiSchema(p)
+					}
+					converted[name] = prop
+				}
+				v = converted
+			}
+		case "items":
+			if items, ok := v.(map[string]interface{}); ok {
+				v = geminiSchema(items)
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
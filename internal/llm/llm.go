@@ -0,0 +1,170 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mini-page/sniprun/config"
+)
+
+// ErrNoAPIKey is returned when a provider needs an API key and none is set
+var ErrNoAPIKey = errors.New("no API key configured")
+
+// ErrInvalidReply is wrapped by errors for replies that arrived but hold no
+// complete answer, e.g. empty or cut off at the token limit
+var ErrInvalidReply = errors.New("invalid reply")
+
+// DefaultGeminiModel is used when no model is configured. The validator
+// needs a model that takes system instructions and a response schema.
+const DefaultGeminiModel = "gemini-2.5-flash"
+
+// Provider sends a prompt to a language model and returns its reply
+type Provider interface {
+	// Name identifies the provider and model in messages
+	Name() string
+	Complete(ctx context.Context, prompt *Prompt) (string, error)
+}
+
+// Prompt is a request to a model. System holds the trusted instructions and
+// User the message, which may contain untrusted data. When Schema is set the
+// provider asks the model for JSON matching it, using the provider's
+// structured output support.
+type Prompt struct {
+	System string
+	User   string
+	Schema map[string]interface{}
+}
+
+// New builds the provider selected in the config
+func New(cfg *config.Config) (Provider, error) {
+	c := cfg.LLM
+
+	key, err := ResolveKey(c.APIKey)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = config.DefaultConfig.LLM.Timeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	switch c.Provider {
+	case "", "gemini":
+		if key == "" {
+			// The old top-level setting also picks up GEMINI_API_KEY
+			key = cfg.GeminiAPIKey
+		}
+		if key == "" {
+			return nil, ErrNoAPIKey
+		}
+		return &Gemini{
+			BaseURL: orDefault(c.BaseURL, "https://generativelanguage.googleapis.com/v1beta"),
+			Model:   orDefault(c.Model, DefaultGeminiModel),
+			APIKey:  key,
+			Client:  client,
+		}, nil
+
+	case "openai":
+		baseURL := orDefault(c.BaseURL, "https://api.openai.com/v1")
+		// Local OpenAI-compatible servers usually run without a key
+		if key == "" && c.BaseURL == "" {
+			return nil, ErrNoAPIKey
+		}
+		return &OpenAI{
+			BaseURL: baseURL,
+			Model:   orDefault(c.Model, "gpt-4o-mini"),
+			APIKey:  key,
+			Client:  client,
+		}, nil
+
+	case "ollama":
+		return &Ollama{
+			BaseURL: orDefault(c.BaseURL, "http://localhost:11434"),
+			Model:   orDefault(c.Model, "llama3"),
+			Client:  client,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown LLM provider '%s' (available: gemini, openai, ollama)", c.Provider)
+	}
+}
+
+// ResolveKey reads an API key reference: "env:NAME" reads an environment
+// variable, "file:PATH" the first line of a file, anything else is used as
+// the key itself
+func ResolveKey(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		return os.Getenv(strings.TrimPrefix(ref, "env:")), nil
+
+	case strings.HasPrefix(ref, "file:"):
+		path := strings.TrimPrefix(ref, "file:")
+		if strings.HasPrefix(path, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			path = filepath.Join(home, path[2:])
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read API key: %w", err)
+		}
+		line, _, _ := strings.Cut(string(data), "\n")
+		return strings.TrimSpace(line), nil
+
+	default:
+		return ref, nil
+	}
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return strings.TrimRight(value, "/")
+}
+
+// postJSON sends body as JSON and decodes a JSON reply into out
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Ollama talks to the chat API of a local Ollama server
+type Ollama struct {
+	BaseURL string
+	Model   string
+	Client  *http.Client
+}
+
+func (o *Ollama) Name() string {
+	return "ollama/" + o.Model
+}
+
+// Endpoint is the API base URL the requests go to
+func (o *Ollama) Endpoint() string {
+	return o.BaseURL
+}
+
+func (o *Ollama) Complete(ctx context.Context, prompt *Prompt) (string, error) {
+	body := map[string]interface{}{
+		"model":    o.Model,
+		"messages": messages(prompt),
+		"stream":   false,
+		"options": map[string]interface{}{
+			"temperature": 0.1,
+		},
+	}
+	if prompt.Schema != nil {
+		body["format"] = prompt.Schema
+	}
+
+	var resp struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		DoneReason string `json:"done_reason"`
+	}
+
+	if err := postJSON(ctx, o.Client, o.BaseURL+"/api/chat", nil, body, &resp); err != nil {
+		return "", err
+	}
+
+	if resp.Message.Content == "" {
+		return "", fmt.Errorf("%w: empty response from API", ErrInvalidReply)
+	}
+	if resp.DoneReason == "length" {
+		return "", fmt.Errorf("%w: reply cut off at the token limit", ErrInvalidReply)
+	}
+	return resp.Message.Content, nil
+}
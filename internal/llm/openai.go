@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// OpenAI talks to any OpenAI-compatible chat completions endpoint. BaseURL
+// includes the version, e.g. https://api.openai.com/v1.
+type OpenAI struct {
+	BaseURL string
+	Model   string
+	APIKey  string
+	Client  *http.Client
+}
+
+func (o *OpenAI) Name() string {
+	return "openai/" + o.Model
+}
+
+// Endpoint is the API base URL the requests go to
+func (o *OpenAI) Endpoint() string {
+	return o.BaseURL
+}
+
+func (o *OpenAI) Complete(ctx context.Context, prompt *Prompt) (string, error) {
+	body := map[string]interface{}{
+		"model":       o.Model,
+		"messages":    messages(prompt),
+		"temperature": 0.1,
+		"max_tokens":  200,
+	}
+	if prompt.Schema != nil {
+		body["response_format"] = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   "response",
+				"strict": true,
+				"schema": prompt.Schema,
+			},
+		}
+	}
+
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+
+	headers := map[string]string{}
+	if o.APIKey != "" {
+		headers["Authorization"] = "Bearer " + o.APIKey
+	}
+
+	if err := postJSON(ctx, o.Client, o.BaseURL+"/chat/completions", headers, body, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("%w: empty response from API", ErrInvalidReply)
+	}
+	if resp.Choices[0].FinishReason == "length" {
+		return "", fmt.Errorf("%w: reply cut off at the token limit", ErrInvalidReply)
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// messages converts a prompt into chat messages
+func messages(prompt *Prompt) []map[string]string {
+	var msgs []map[string]string
+	if prompt.System != "" {
+		msgs = append(msgs, map[string]string{"role": "system", "content": prompt.System})
+	}
+	return append(msgs, map[string]string{"role": "user", "content": prompt.User})
+}
@@ -0,0 +1,70 @@
+package pin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Pin records the command of a community snip the user has run or approved.
+// Community snips are replaced by every update, so a command that no longer
+// matches its pin must be approved again before it runs.
+type Pin struct {
+	Hash     string    `yaml:"hash"`
+	Command  string    `yaml:"command"`
+	Approved time.Time `yaml:"approved"`
+}
+
+// pinsFile is where pins are stored, relative to the config dir
+const pinsFile = "pins.yaml"
+
+// Hash returns the hash a command is pinned by
+func Hash(command string) string {
+	sum := sha256.Sum256([]byte(command))
+	return hex.EncodeToString(sum[:])
+}
+
+// New pins a command approved now
+func New(command string) *Pin {
+	return &Pin{Hash: Hash(command), Command: command, Approved: time.Now()}
+}
+
+// Matches reports whether a command is the one that was pinned
+func (p *Pin) Matches(command string) bool {
+	return p.Hash == Hash(command)
+}
+
+// Load reads all pins from the config dir, keyed by snip name
+func Load(configDir string) (map[string]*Pin, error) {
+	data, err := os.ReadFile(filepath.Join(configDir, pinsFile))
+	if os.IsNotExist(err) {
+		return map[string]*Pin{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pins: %w", err)
+	}
+
+	pins := map[string]*Pin{}
+	if err := yaml.Unmarshal(data, &pins); err != nil {
+		return nil, fmt.Errorf("failed to parse pins: %w", err)
+	}
+	return pins, nil
+}
+
+// Save writes all pins to the config dir
+func Save(configDir string, pins map[string]*Pin) error {
+	data, err := yaml.Marshal(pins)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pins: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(configDir, pinsFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write pins: %w", err)
+	}
+	return nil
+}
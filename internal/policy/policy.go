@@ -0,0 +1,355 @@
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mini-page/sniprun/internal/security"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SystemPath is the policy file shared by all users of a machine
+var SystemPath = "/etc/sniprun/policy.yaml"
+
+// Action is what a matching rule does with a command
+type Action string
+
+const (
+	// Deny blocks the command without asking
+	Deny Action = "deny"
+	// Warn asks for confirmation even if the validators find nothing
+	Warn Action = "warn"
+	// Allow runs the command without calling the validators
+	Allow Action = "allow"
+)
+
+// Confirmation is when a command needs to be confirmed before it runs
+type Confirmation string
+
+const (
+	// ConfirmAlways asks before every run
+	ConfirmAlways Confirmation = "always"
+	// ConfirmWarning asks when the check finds a risk, the default
+	ConfirmWarning Confirmation = "warning"
+	// ConfirmNever runs commands with warnings without asking. Dangerous
+	// commands stay blocked.
+	ConfirmNever Confirmation = "never"
+)
+
+// confirmRanks orders confirmations from least to most strict
+var confirmRanks = map[Confirmation]int{"": 0, ConfirmNever: 1, ConfirmWarning: 2, ConfirmAlways: 3}
+
+// TrustSettings attach behaviour to a trust level
+type TrustSettings struct {
+	Confirm Confirmation `yaml:"confirm"`
+}
+
+// Rule matches commands by any combination of a regular expression, a
+// command name, a snip category and a trust level. All fields that are set
+// must match.
+type Rule struct {
+	ID       string `yaml:"id"`
+	Action   Action `yaml:"action"`
+	Pattern  string `yaml:"pattern,omitempty"`
+	Command  string `yaml:"command,omitempty"`
+	Category string `yaml:"category,omitempty"`
+	Trust    string `yaml:"trust,omitempty"`
+	Reason   string `yaml:"reason,omitempty"`
+
+	// Source is the policy file the rule was loaded from
+	Source string `yaml:"-"`
+
+	pattern *regexp.Regexp
+}
+
+// Settings are switches that apply regardless of the rules
+type Settings struct {
+	// CommunityRequiresValidation refuses to run community snips without a
+	// successful security check. Allow rules do not skip it and validator
+	// errors block the command instead of being ignored.
+	CommunityRequiresValidation bool `yaml:"community_requires_validation"`
+	// ForbidSkipCheck rejects --skip-check
+	ForbidSkipCheck bool `yaml:"forbid_skip_check"`
+	// SandboxTrust lists trust levels whose snips always run sandboxed
+	SandboxTrust []string `yaml:"sandbox_trust"`
+	// Trust configures each trust level, e.g. community snips always need
+	// confirmation while verified ones never do
+	Trust map[string]TrustSettings `yaml:"trust"`
+	// AllowYes lists trust levels whose confirmations --yes may answer.
+	// When both files set it, only levels listed in both are allowed.
+	AllowYes []string `yaml:"allow_yes"`
+}
+
+// file is the layout of a policy file
+type file struct {
+	Settings Settings `yaml:"settings"`
+	Rules    []*Rule  `yaml:"rules"`
+}
+
+// Policy is the combination of the system and user policy files. System
+// rules are evaluated first and a setting enabled in either file applies.
+type Policy struct {
+	Settings
+	Rules []*Rule
+	Files []string
+
+	// Version changes whenever the content of a policy file changes
+	Version string
+}
+
+// Target is the command a policy is evaluated against
+type Target struct {
+	Command  string
+	Category string
+	Trust    string
+}
+
+// Decision is the outcome of evaluating a policy. Rule is nil when no rule
+// matched and the validators decide.
+type Decision struct {
+	Action Action
+	Rule   *Rule
+}
+
+// UserPath returns the policy file in the config dir
+func UserPath(configDir string) string {
+	return filepath.Join(configDir, "policy.yaml")
+}
+
+// Load reads the system policy and the user policy, skipping files that do
+// not exist
+func Load(configDir string) (*Policy, error) {
+	p := &Policy{}
+	hash := sha256.New()
+	allowYesSet := false
+
+	for _, path := range []string{SystemPath, UserPath(configDir)} {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy: %w", err)
+		}
+
+		var f file
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("failed to parse policy %s: %w", path, err)
+		}
+
+		for i, r := range f.Rules {
+			if err := r.compile(); err != nil {
+				return nil, fmt.Errorf("invalid rule %d in %s: %w", i+1, path, err)
+			}
+			r.Source = path
+		}
+
+		p.CommunityRequiresValidation = p.CommunityRequiresValidation || f.Settings.CommunityRequiresValidation
+		p.ForbidSkipCheck = p.ForbidSkipCheck || f.Settings.ForbidSkipCheck
+		p.SandboxTrust = append(p.SandboxTrust, f.Settings.SandboxTrust...)
+		if err := p.mergeTrust(f.Settings.Trust); err != nil {
+			return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+		}
+		if f.Settings.AllowYes != nil {
+			if allowYesSet {
+				p.AllowYes = intersect(p.AllowYes, f.Settings.AllowYes)
+			} else {
+				p.AllowYes = f.Settings.AllowYes
+			}
+			allowYesSet = true
+		}
+		p.Rules = append(p.Rules, f.Rules...)
+		p.Files = append(p.Files, path)
+
+		fmt.Fprintf(hash, "%s\n%d\n", path, len(data))
+		hash.Write(data)
+	}
+
+	p.Version = hex.EncodeToString(hash.Sum(nil))[:16]
+	return p, nil
+}
+
+func (r *Rule) compile() error {
+	switch r.Action {
+	case Deny, Warn, Allow:
+	default:
+		return fmt.Errorf("action must be deny, warn or allow, got '%s'", r.Action)
+	}
+
+	if r.Pattern == "" && r.Command == "" && r.Category == "" && r.Trust == "" {
+		return fmt.Errorf("rule '%s' needs at least one of pattern, command, category or trust", r.ID)
+	}
+
+	if r.Pattern != "" {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		r.pattern = re
+	}
+
+	if r.ID == "" {
+		r.ID = string(r.Action) + ":" + r.describe()
+	}
+	return nil
+}
+
+// describe summarises what a rule matches
+func (r *Rule) describe() string {
+	var parts []string
+	if r.Pattern != "" {
+		parts = append(parts, "pattern="+r.Pattern)
+	}
+	if r.Command != "" {
+		parts = append(parts, "command="+r.Command)
+	}
+	if r.Category != "" {
+		parts = append(parts, "category="+r.Category)
+	}
+	if r.Trust != "" {
+		parts = append(parts, "trust="+r.Trust)
+	}
+	return strings.Join(parts, ",")
+}
+
+// String describes the rule for messages
+func (r *Rule) String() string {
+	s := fmt.Sprintf("'%s' in %s", r.ID, r.Source)
+	if r.Reason != "" {
+		s += ": " + r.Reason
+	}
+	return s
+}
+
+// Matches reports whether the rule applies to a target
+func (r *Rule) Matches(t *Target) bool {
+	if r.pattern != nil && !r.pattern.MatchString(t.Command) {
+		return false
+	}
+	if r.Category != "" && r.Category != t.Category {
+		return false
+	}
+	if r.Trust != "" && r.Trust != t.Trust {
+		return false
+	}
+	if r.Command != "" && !containsCommand(t.Command, r.Command) {
+		return false
+	}
+	return true
+}
+
+// containsCommand reports whether any simple command in a command line has
+// the given name
+func containsCommand(command, name string) bool {
+	names, err := security.CommandNames(command)
+	if err != nil {
+		// Fall back to the first word for commands the parser rejects
+		fields := strings.Fields(command)
+		names = fields[:min(1, len(fields))]
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate returns the first rule matching the target
+func (p *Policy) Evaluate(t *Target) Decision {
+	for _, r := range p.Rules {
+		if r.Matches(t) {
+			return Decision{Action: r.Action, Rule: r}
+		}
+	}
+	return Decision{}
+}
+
+// RequiresValidation reports whether commands with a trust level must pass
+// the security check
+func (p *Policy) RequiresValidation(trust string) bool {
+	return p.CommunityRequiresValidation && trust == "community"
+}
+
+// SandboxRequired reports whether snips with a trust level must run in the
+// sandbox
+func (p *Policy) SandboxRequired(trust string) bool {
+	for _, t := range p.SandboxTrust {
+		if t == trust {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeTrust adds the trust settings of a policy file, keeping the stricter
+// confirmation when both files set one
+func (p *Policy) mergeTrust(settings map[string]TrustSettings) error {
+	for trust, s := range settings {
+		if _, ok := confirmRanks[s.Confirm]; !ok {
+			return fmt.Errorf("confirm for %s must be always, warning or never, got '%s'", trust, s.Confirm)
+		}
+
+		if p.Trust == nil {
+			p.Trust = make(map[string]TrustSettings)
+		}
+		current := p.Trust[trust]
+		if confirmRanks[s.Confirm] > confirmRanks[current.Confirm] {
+			current.Confirm = s.Confirm
+		}
+		p.Trust[trust] = current
+	}
+	return nil
+}
+
+// Confirmation returns when commands of snips with a trust level need to be
+// confirmed
+func (p *Policy) Confirmation(trust string) Confirmation {
+	if c := p.Trust[trust].Confirm; c != "" {
+		return c
+	}
+	return ConfirmWarning
+}
+
+// intersect returns the values found in both lists
+func intersect(a, b []string) []string {
+	kept := []string{}
+	for _, v := range a {
+		for _, w := range b {
+			if v == w {
+				kept = append(kept, v)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// CheckYes returns an error if the policy does not let --yes answer the
+// confirmations of snips with a trust level
+func (p *Policy) CheckYes(trust string) error {
+	for _, t := range p.AllowYes {
+		if t == trust {
+			return nil
+		}
+	}
+	return fmt.Errorf("--yes is not allowed for %s snips by the security policy (see allow_yes)", trust)
+}
+
+// CheckSkip returns an error if the policy does not allow skipping the
+// security check for a trust level
+func (p *Policy) CheckSkip(trust string) error {
+	if p.ForbidSkipCheck {
+		return fmt.Errorf("--skip-check is forbidden by the security policy")
+	}
+	if p.RequiresValidation(trust) {
+		return fmt.Errorf("community snips require validation, --skip-check is not allowed")
+	}
+	return nil
+}
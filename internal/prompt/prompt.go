@@ -0,0 +1,156 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ExitNoInput is the exit status when a question needs an answer that
+// cannot be asked for, so scripts can tell it apart from a failed command
+const ExitNoInput = 3
+
+// EnvNonInteractive disables all questions when set to anything but "",
+// "0" or "false", like --no-input
+const EnvNonInteractive = "SNIPRUN_NONINTERACTIVE"
+
+var (
+	// AssumeYes answers yes/no questions with yes (--yes)
+	AssumeYes bool
+	// NoInput never reads answers from stdin (--no-input)
+	NoInput bool
+	// Banner is shown in front of every question, see SetBanner
+	Banner string
+)
+
+// colors are the ANSI codes of banner colours
+var colors = map[string]string{
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+}
+
+// SetBanner shows text in front of every question, in colour when stdout is
+// a terminal and $NO_COLOR is not set
+func SetBanner(text, color string) error {
+	code, ok := colors[strings.ToLower(color)]
+	if color != "" && !ok {
+		return fmt.Errorf("unknown colour '%s', use red, green, yellow, blue, magenta or cyan", color)
+	}
+
+	Banner = text
+	if ok && os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(os.Stdout.Fd())) {
+		Banner = "\033[1;" + code + "m" + text + "\033[0m"
+	}
+	return nil
+}
+
+// Label puts the banner in front of a question
+func Label(question string) string {
+	if Banner == "" {
+		return question
+	}
+	return Banner + " " + question
+}
+
+// ErrNoInput is wrapped by the errors of questions that cannot be asked
+var ErrNoInput = errors.New("input required but running non-interactively")
+
+// Interactive reports whether questions can be asked: stdin is a terminal
+// and neither --no-input nor $SNIPRUN_NONINTERACTIVE is set
+func Interactive() bool {
+	if NoInput {
+		return false
+	}
+	switch strings.ToLower(os.Getenv(EnvNonInteractive)) {
+	case "", "0", "false":
+	default:
+		return false
+	}
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// unanswerable is the error for a question that cannot be asked
+func unanswerable(question, hint string) error {
+	question = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(question), ":"))
+	if hint != "" {
+		return fmt.Errorf("%w: %s (%s)", ErrNoInput, question, hint)
+	}
+	return fmt.Errorf("%w: %s", ErrNoInput, question)
+}
+
+// Confirm asks a yes/no question. --yes answers it; without a terminal it
+// fails with ErrNoInput rather than guessing.
+func Confirm(question string) (bool, error) {
+	if AssumeYes {
+		fmt.Printf("%s (yes/no): yes\n", Label(question))
+		return true, nil
+	}
+	if !Interactive() {
+		return false, unanswerable(question, "use --yes to answer yes")
+	}
+
+	fmt.Printf("%s (yes/no): ", Label(question))
+	answer, err := ReadLine()
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "yes" || answer == "y", nil
+}
+
+// Line asks for a line of text
+func Line(label string) (string, error) {
+	if !Interactive() {
+		return "", unanswerable(label, "")
+	}
+
+	fmt.Print(Label(label))
+	return ReadLine()
+}
+
+// Secret asks for a value without echoing it. The label goes to stderr so
+// it does not end up in output meant for eval.
+func Secret(label string) (string, error) {
+	if !Interactive() {
+		return "", unanswerable(label, "")
+	}
+
+	fd := int(os.Stdin.Fd())
+	fmt.Fprint(os.Stderr, Label(label))
+	value, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read value: %w", err)
+	}
+	return string(value), nil
+}
+
+// ReadLine reads one line from stdin a byte at a time, so nothing meant for
+// a later question or the snip command is buffered away
+func ReadLine() (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 1 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if err != nil {
+			if len(line) == 0 {
+				return "", fmt.Errorf("failed to read answer: %w", err)
+			}
+			break
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mini-page/sniprun/internal/keyring"
+)
+
+const (
+	// ManifestName is the manifest at the root of the snips repository. It
+	// lists the SHA-256 of every snip file by its path in the repository.
+	ManifestName = "manifest.json"
+	// SignatureName holds the base64 ed25519 signature of the manifest bytes
+	SignatureName = "manifest.sig"
+)
+
+// ErrUnsigned is returned for archives without a manifest or signature
+var ErrUnsigned = errors.New("archive is not signed")
+
+// Manifest lists the files covered by a signature
+type Manifest struct {
+	Files map[string]string `json:"files"`
+}
+
+// VerifyManifest checks the signature of a manifest against the trusted keys
+// and returns the manifest with the key that signed it
+func VerifyManifest(data, sig []byte, keys []*keyring.Key) (*Manifest, *keyring.Key, error) {
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest signature: %w", err)
+	}
+
+	var signer *keyring.Key
+	for _, k := range keys {
+		if k.Verify(data, signature) {
+			signer = k
+			break
+		}
+	}
+	if signer == nil {
+		return nil, nil, fmt.Errorf("manifest signature does not match any trusted key")
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return &m, signer, nil
+}
+
+// Check compares the content of a file with its hash in the manifest. Files
+// the manifest does not list are reported with listed set to false.
+func (m *Manifest) Check(name string, data []byte) (listed bool, err error) {
+	want, ok := m.Files[name]
+	if !ok {
+		return false, nil
+	}
+
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return true, fmt.Errorf("%s does not match the signed manifest", name)
+	}
+	return true, nil
+}
@@ -1,120 +1,217 @@
-package repo
-
-import (
-	"archive/zip"
-	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
-)
-
-const (
-	// GitHub repository for community snips
-	RepoOwner = "sniprun"
-	RepoName  = "snips"
-	RepoBranch = "main"
-)
-
-// SyncCommunitySnips downloads community snips from GitHub
-func SyncCommunitySnips(targetDir string) (int, error) {
-	// Download zip archive from GitHub
-	zipURL := fmt.Sprintf("https://github.com/%s/%s/archive/refs/heads/%s.zip", 
-		RepoOwner, RepoName, RepoBranch)
-
-	fmt.Printf("Downloading from: %s\n", zipURL)
-
-	resp, err := http.Get(zipURL)
-	if err != nil {
-		return 0, fmt.Errorf("failed to download repository: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("failed to download: HTTP %d", resp.StatusCode)
-	}
-
-	// Create temporary file
-	tmpFile, err := os.CreateTemp("", "sniprun-*.zip")
-	if err != nil {
-		return 0, fmt.Errorf("failed to create temp file: %w", err)
-	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
-
-	// Download to temp file
-	if _, err := io.Copy(tmpFile, resp.Body); err != nil {
-		return 0, fmt.Errorf("failed to save download: %w", err)
-	}
-
-	// Extract YAML files
-	count, err := extractSnips(tmpFile.Name(), targetDir)
-	if err != nil {
-		return 0, fmt.Errorf("failed to extract snips: %w", err)
-	}
-
-	return count, nil
-}
-
-// extractSnips extracts .yaml files from zip to target directory
-func extractSnips(zipPath, targetDir string) (int, error) {
-	r, err := zip.OpenReader(zipPath)
-	if err != nil {
-		return 0, err
-	}
-	defer r.Close()
-
-	count := 0
-
-	// Clean target directory first
-	if err := os.RemoveAll(targetDir); err != nil {
-		return 0, err
-	}
-	if err := os.MkdirAll(targetDir, 0755); err != nil {
-		return 0, err
-	}
-
-	for _, f := range r.File {
-		// Only extract .yaml files
-		if !strings.HasSuffix(f.Name, ".yaml") {
-			continue
-		}
-
-		// Skip directories and hidden files
-		if f.FileInfo().IsDir() || strings.Contains(filepath.Base(f.Name), ".") && !strings.HasSuffix(f.Name, ".yaml") {
-			continue
-		}
-
-		// Get just the filename
-		filename := filepath.Base(f.Name)
-		targetPath := filepath.Join(targetDir, filename)
-
-		// Extract file
-		if err := extractFile(f, targetPath); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to extract %s: %v\n", filename, err)
-			continue
-		}
-
-		count++
-	}
-
-	return count, nil
-}
-
-func extractFile(f *zip.File, targetPath string) error {
-	rc, err := f.Open()
-	if err != nil {
-		return err
-	}
-	defer rc.Close()
-
-	outFile, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-	if err != nil {
-		return err
-	}
-	defer outFile.Close()
-
-	_, err = io.Copy(outFile, rc)
-	return err
-}
\ No newline at end of file
+package repo
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mini-page/sniprun/internal/keyring"
+	"github.com/mini-page/sniprun/internal/snip"
+)
+
+const (
+	// GitHub repository for community snips
+	RepoOwner = "sniprun"
+	RepoName  = "snips"
+	RepoBranch = "main"
+)
+
+// SyncOptions controls how the downloaded archive is verified
+type SyncOptions struct {
+	// Keys are the trusted keys a manifest must be signed with
+	Keys []*keyring.Key
+	// AllowUnsigned accepts archives without a signature, or when no keys
+	// are trusted. Their snips are never verified. Archives whose signature
+	// or files do not match are rejected regardless.
+	AllowUnsigned bool
+}
+
+// SyncResult describes a successful sync
+type SyncResult struct {
+	Count    int
+	Verified int
+	// Signer is the key that signed the manifest, nil for unsigned archives
+	Signer *keyring.Key
+}
+
+// SyncCommunitySnips downloads community snips from GitHub, verifies them
+// against the signed manifest and replaces the snips in targetDir
+func SyncCommunitySnips(targetDir string, opts SyncOptions) (*SyncResult, error) {
+	// Download zip archive from GitHub
+	zipURL := fmt.Sprintf("https://github.com/%s/%s/archive/refs/heads/%s.zip", 
+		RepoOwner, RepoName, RepoBranch)
+
+	fmt.Printf("Downloading from: %s\n", zipURL)
+
+	resp, err := http.Get(zipURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download repository: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download: HTTP %d", resp.StatusCode)
+	}
+
+	// Create temporary file
+	tmpFile, err := os.CreateTemp("", "sniprun-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	// Download to temp file
+	if _, err := io.Copy(tmpFile, resp.Body); err != nil {
+		return nil, fmt.Errorf("failed to save download: %w", err)
+	}
+
+	return InstallArchive(tmpFile.Name(), targetDir, opts)
+}
+
+// InstallArchive verifies a snips repository archive and replaces the snips
+// in targetDir with its YAML files. Nothing is changed if verification
+// fails.
+func InstallArchive(zipPath, targetDir string, opts SyncOptions) (*SyncResult, error) {
+	files, manifest, sig, err := readArchive(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	result := &SyncResult{}
+	verified := &snip.Verified{Files: map[string]string{}}
+
+	switch {
+	case manifest == nil || sig == nil:
+		if !opts.AllowUnsigned {
+			return nil, ErrUnsigned
+		}
+	case len(opts.Keys) == 0:
+		if !opts.AllowUnsigned {
+			return nil, fmt.Errorf("no trusted keys to verify the archive, add one with 'sniprun trust add-key'")
+		}
+	default:
+		m, signer, err := VerifyManifest(manifest, sig, opts.Keys)
+		if err != nil {
+			return nil, err
+		}
+		result.Signer = signer
+		verified.Key = signer.Name
+
+		// Every signed file must be present and unchanged, files added
+		// outside the signature are dropped
+		for name := range m.Files {
+			if _, ok := files[name]; !ok {
+				return nil, fmt.Errorf("%s is listed in the signed manifest but missing from the archive", name)
+			}
+		}
+		for name, data := range files {
+			listed, err := m.Check(name, data)
+			if err != nil {
+				return nil, err
+			}
+			if !listed {
+				fmt.Fprintf(os.Stderr, "Warning: skipping %s, it is not covered by the signature\n", name)
+				delete(files, name)
+				continue
+			}
+			verified.Files[filepath.Base(name)] = m.Files[name]
+		}
+	}
+
+	// Snips are installed flat by file name, so two paths with the same
+	// name would overwrite each other
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	installed := map[string]string{}
+	for _, name := range names {
+		base := filepath.Base(name)
+		if other, ok := installed[base]; ok {
+			return nil, fmt.Errorf("%s and %s would both be installed as %s", other, name, base)
+		}
+		installed[base] = name
+	}
+
+	// Clean target directory first
+	if err := os.RemoveAll(targetDir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return nil, err
+	}
+
+	for name, data := range files {
+		filename := filepath.Base(name)
+		if err := os.WriteFile(filepath.Join(targetDir, filename), data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to extract %s: %v\n", filename, err)
+			delete(verified.Files, filename)
+			continue
+		}
+		result.Count++
+	}
+
+	if result.Signer != nil {
+		if err := snip.SaveVerified(targetDir, verified); err != nil {
+			return nil, fmt.Errorf("failed to record verified snips: %w", err)
+		}
+		result.Verified = len(verified.Files)
+	}
+
+	return result, nil
+}
+
+// readArchive returns the YAML files of a repository archive by their path
+// inside the repository, along with the manifest and its signature if
+// present
+func readArchive(zipPath string) (files map[string][]byte, manifest, sig []byte, err error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer r.Close()
+
+	files = make(map[string][]byte)
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		// GitHub archives put everything under a "<repo>-<branch>/" dir
+		name := f.Name
+		if i := strings.Index(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+
+		switch {
+		case name == ManifestName:
+			manifest, err = readZipFile(f)
+		case name == SignatureName:
+			sig, err = readZipFile(f)
+		case strings.HasSuffix(name, ".yaml") && !strings.HasPrefix(filepath.Base(name), "."):
+			files[name], err = readZipFile(f)
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return files, manifest, sig, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,270 @@
+package runlog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/secrets"
+)
+
+// fileTimeFormat prefixes log file names so they sort by creation time
+const fileTimeFormat = "20060102-150405.000"
+
+// TimeFormat is the timestamp written at the start of every captured line
+const TimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Dir returns the directory holding captured run output
+func Dir(configDir string) string {
+	return filepath.Join(configDir, "logs")
+}
+
+// Log captures the output of a single run to a file. Every line is prefixed
+// with a timestamp and the stream it came from, and writing stops once the
+// file reaches its size cap.
+type Log struct {
+	Path string
+
+	mu        sync.Mutex
+	f         *os.File
+	written   int64
+	maxBytes  int64
+	truncated bool
+	streams   []*stream
+	hidden    []string
+}
+
+// Create starts a new log file for a run of the named snip. A maxBytes of
+// zero or less disables the size cap.
+func Create(configDir, snipName string, maxBytes int64) (*Log, error) {
+	dir := Dir(configDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.log", time.Now().Format(fileTimeFormat), sanitize(snipName))
+	path := filepath.Join(dir, name)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log: %w", err)
+	}
+
+	return &Log{Path: path, f: f, maxBytes: maxBytes}, nil
+}
+
+// Hide redacts secret values from everything written to the log after it
+func (l *Log) Hide(values []string) {
+	l.mu.Lock()
+	l.hidden = append(l.hidden, values...)
+	l.mu.Unlock()
+}
+
+// Header writes a comment line describing the run, such as the command
+func (l *Log) Header(format string, args ...interface{}) {
+	l.writeLine("#", fmt.Sprintf(format, args...))
+}
+
+// Stream returns a writer that timestamps each line written to it and tags
+// it with the given stream name, e.g. "out" or "err"
+func (l *Log) Stream(name string) io.Writer {
+	s := &stream{log: l, name: name}
+
+	l.mu.Lock()
+	l.streams = append(l.streams, s)
+	l.mu.Unlock()
+
+	return s
+}
+
+// Close flushes partial lines, records how the run ended and closes the file
+func (l *Log) Close(exitCode int, duration time.Duration) error {
+	for _, s := range l.streams {
+		s.flush()
+	}
+
+	l.writeLine("#", fmt.Sprintf("exit %d after %s", exitCode, duration.Round(time.Millisecond)))
+	return l.f.Close()
+}
+
+func (l *Log) writeLine(tag, text string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	line := fmt.Sprintf("%s %s | %s\n", time.Now().Format(TimeFormat), tag, secrets.Redact(text, l.hidden))
+
+	// Header and footer comments are always kept so a truncated log still
+	// says how the run ended
+	if tag != "#" {
+		if l.truncated {
+			return
+		}
+		if l.maxBytes > 0 && l.written+int64(len(line)) > l.maxBytes {
+			l.truncated = true
+			line = fmt.Sprintf("%s # | output truncated at %d bytes\n", time.Now().Format(TimeFormat), l.maxBytes)
+		}
+	}
+
+	n, _ := l.f.WriteString(line)
+	l.written += int64(n)
+}
+
+// stream splits written bytes into lines for the log
+type stream struct {
+	log  *Log
+	name string
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *stream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf.Write(p)
+	for {
+		i := bytes.IndexByte(s.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := string(s.buf.Next(i + 1))
+		s.log.writeLine(s.name, strings.TrimRight(line, "\r\n"))
+	}
+
+	return len(p), nil
+}
+
+func (s *stream) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buf.Len() > 0 {
+		s.log.writeLine(s.name, s.buf.String())
+		s.buf.Reset()
+	}
+}
+
+// Latest returns the file name of the most recent log of the named snip,
+// including runs that are still in progress
+func Latest(configDir, snipName string) (string, error) {
+	names, err := logNames(configDir)
+	if err != nil {
+		return "", err
+	}
+
+	for i := len(names) - 1; i >= 0; i-- {
+		name := names[i]
+		if len(name) > len(fileTimeFormat)+1 && strings.TrimSuffix(name[len(fileTimeFormat)+1:], ".log") == sanitize(snipName) {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// logNames lists log file names, oldest first
+func logNames(configDir string) ([]string, error) {
+	entries, err := os.ReadDir(Dir(configDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".log") {
+			names = append(names, e.Name())
+		}
+	}
+
+	// Names start with the creation time, so they sort oldest first
+	sort.Strings(names)
+	return names, nil
+}
+
+// Prune deletes the oldest logs so that at most keep remain
+func Prune(configDir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	names, err := logNames(configDir)
+	if err != nil {
+		return err
+	}
+
+	for len(names) > keep {
+		if err := os.Remove(filepath.Join(Dir(configDir), names[0])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		names = names[1:]
+	}
+
+	return nil
+}
+
+// OpenAppend opens a log that every run appends to, such as the log of a
+// scheduled job. Once it reaches maxBytes it is moved to path.1, replacing
+// the previous one, so at most two files of about maxBytes are kept. A
+// maxBytes of zero or less disables rotation.
+func OpenAppend(path string, maxBytes int64) (*os.File, error) {
+	if info, err := os.Stat(path); err == nil && maxBytes > 0 && info.Size() >= maxBytes {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return nil, fmt.Errorf("failed to rotate log: %w", err)
+		}
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+}
+
+// Limit returns a writer that passes on the first maxBytes written to w and
+// then a single note that the rest was dropped. A maxBytes of zero or less
+// disables the limit.
+func Limit(w io.Writer, maxBytes int64) io.Writer {
+	if maxBytes <= 0 {
+		return w
+	}
+	return &limited{w: w, left: maxBytes, maxBytes: maxBytes}
+}
+
+type limited struct {
+	w        io.Writer
+	left     int64
+	maxBytes int64
+	dropped  bool
+}
+
+func (l *limited) Write(p []byte) (int, error) {
+	if l.dropped {
+		return len(p), nil
+	}
+	if int64(len(p)) <= l.left {
+		n, err := l.w.Write(p)
+		l.left -= int64(n)
+		return n, err
+	}
+
+	if _, err := l.w.Write(p[:l.left]); err != nil {
+		return 0, err
+	}
+	l.left = 0
+	l.dropped = true
+	fmt.Fprintf(l.w, "\n... output truncated at %d bytes\n", l.maxBytes)
+	return len(p), nil
+}
+
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
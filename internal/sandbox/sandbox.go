@@ -0,0 +1,69 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// HelperArg is the first argument sniprun re-executes itself with to set up
+// the sandbox before starting the command
+const HelperArg = "__sniprun-sandbox"
+
+// Config selects the restrictions applied to a sandboxed command
+type Config struct {
+	// AllowNetwork keeps network access, otherwise the command runs in an
+	// empty network namespace
+	AllowNetwork bool `json:"allow_network"`
+	// Writable lists the paths that stay writable, usually the working
+	// directory. Everything else is read-only.
+	Writable []string `json:"writable"`
+	Limits   Limits   `json:"limits"`
+	// Strict refuses to run the command if a restriction is unavailable
+	// instead of reporting it and continuing
+	Strict bool `json:"strict"`
+}
+
+// Limits are resource limits for a sandboxed command. Zero leaves a limit
+// unchanged.
+type Limits struct {
+	CPUSeconds uint64 `json:"cpu_seconds"`
+	FileSize   uint64 `json:"file_size"`
+	OpenFiles  uint64 `json:"open_files"`
+	Processes  uint64 `json:"processes"`
+}
+
+// report prints a restriction that could not be applied. In strict mode
+// the sandbox gives up instead.
+func report(cfg *Config, w io.Writer, feature string, err error) error {
+	if cfg.Strict {
+		return fmt.Errorf("sandbox: %s unavailable: %w", feature, err)
+	}
+	fmt.Fprintf(w, "⚠️  Sandbox: %s unavailable: %v\n", feature, err)
+	return nil
+}
+
+// RunHelper is the entry point of the re-executed sniprun process. It never
+// returns: it either replaces itself with the command or exits.
+func RunHelper(args []string) {
+	if len(args) == 1 && args[0] == "probe" {
+		os.Exit(0)
+	}
+
+	if len(args) < 3 || args[1] != "--" {
+		fmt.Fprintln(os.Stderr, "sniprun sandbox: invalid arguments")
+		os.Exit(126)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal([]byte(args[0]), &cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "sniprun sandbox: invalid config: %v\n", err)
+		os.Exit(126)
+	}
+
+	if err := enter(&cfg, args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "sniprun sandbox: %v\n", err)
+		os.Exit(126)
+	}
+}
@@ -0,0 +1,222 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	probeOnce sync.Once
+	probeErr  error
+)
+
+// namespaceAttr runs the helper in a new user namespace, mapping the caller
+// to the same ids, plus a network namespace unless the network is allowed
+func namespaceAttr(allowNetwork bool) *syscall.SysProcAttr {
+	flags := uintptr(syscall.CLONE_NEWUSER)
+	if !allowNetwork {
+		flags |= syscall.CLONE_NEWNET
+	}
+
+	return &syscall.SysProcAttr{
+		Cloneflags:                 flags,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+	}
+}
+
+// probeNamespaces checks once whether this kernel lets us create the
+// namespaces, which distributions can disable for unprivileged users
+func probeNamespaces(self string) error {
+	probeOnce.Do(func() {
+		cmd := exec.Command(self, HelperArg, "probe")
+		cmd.SysProcAttr = namespaceAttr(false)
+		if err := cmd.Run(); err != nil {
+			probeErr = fmt.Errorf("cannot create user and network namespaces: %w", err)
+		}
+	})
+	return probeErr
+}
+
+// Command returns a command that runs argv inside the sandbox. Restrictions
+// that the kernel does not support are reported to warnings, or make
+// Command fail in strict mode.
+func Command(cfg *Config, argv []string, warnings io.Writer) (*exec.Cmd, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("sandbox: cannot find the sniprun executable: %w", err)
+	}
+
+	spec, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(self, append([]string{HelperArg, string(spec), "--"}, argv...)...)
+
+	if err := probeNamespaces(self); err != nil {
+		feature := "network isolation"
+		if cfg.AllowNetwork {
+			feature = "user namespace"
+		}
+		if err := report(cfg, warnings, feature, err); err != nil {
+			return nil, err
+		}
+	} else {
+		cmd.SysProcAttr = namespaceAttr(cfg.AllowNetwork)
+	}
+
+	return cmd, nil
+}
+
+// enter applies the restrictions to the current process and replaces it
+// with the command
+func enter(cfg *Config, argv []string) error {
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		return err
+	}
+
+	if err := setLimits(cfg.Limits); err != nil {
+		if err := report(cfg, os.Stderr, "resource limits", err); err != nil {
+			return err
+		}
+	}
+
+	// Required for Landlock without privileges, and keeps setuid binaries
+	// from regaining what is dropped below
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		if err := report(cfg, os.Stderr, "no_new_privs", err); err != nil {
+			return err
+		}
+	}
+
+	if err := restrictFilesystem(cfg.Writable); err != nil {
+		if err := report(cfg, os.Stderr, "read-only filesystem", err); err != nil {
+			return err
+		}
+	}
+
+	if err := dropCapabilities(); err != nil {
+		if err := report(cfg, os.Stderr, "capability dropping", err); err != nil {
+			return err
+		}
+	}
+
+	return syscall.Exec(path, argv, os.Environ())
+}
+
+func setLimits(limits Limits) error {
+	for resource, value := range map[int]uint64{
+		unix.RLIMIT_CPU:    limits.CPUSeconds,
+		unix.RLIMIT_FSIZE:  limits.FileSize,
+		unix.RLIMIT_NOFILE: limits.OpenFiles,
+		unix.RLIMIT_NPROC:  limits.Processes,
+	} {
+		if value == 0 {
+			continue
+		}
+
+		var current unix.Rlimit
+		if err := unix.Getrlimit(resource, &current); err != nil {
+			return err
+		}
+		// Never raise a limit above the one we were given
+		if current.Max != unix.RLIM_INFINITY && value > current.Max {
+			value = current.Max
+		}
+		if err := unix.Setrlimit(resource, &unix.Rlimit{Cur: value, Max: value}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// landlockWriteAccess are the Landlock rights that modify the filesystem,
+// by the ABI version that introduced them
+var landlockWriteAccess = []uint64{
+	1: unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE | unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR | unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK | unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK | unix.LANDLOCK_ACCESS_FS_MAKE_SYM,
+	2: unix.LANDLOCK_ACCESS_FS_REFER,
+	3: unix.LANDLOCK_ACCESS_FS_TRUNCATE,
+}
+
+// restrictFilesystem uses Landlock to deny writes outside the writable
+// paths. /dev stays writable for /dev/null and the terminal.
+func restrictFilesystem(writable []string) error {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		if errno == unix.ENOSYS || errno == unix.EOPNOTSUPP {
+			return errors.New("Landlock is not supported or not enabled by this kernel")
+		}
+		return fmt.Errorf("Landlock: %w", errno)
+	}
+
+	var access uint64
+	for v := 1; v < len(landlockWriteAccess) && v <= int(abi); v++ {
+		access |= landlockWriteAccess[v]
+	}
+
+	attr := unix.LandlockRulesetAttr{Access_fs: access}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("Landlock: %w", errno)
+	}
+	defer unix.Close(int(fd))
+
+	for _, path := range append([]string{"/dev"}, writable...) {
+		dir, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return fmt.Errorf("cannot open %s: %w", path, err)
+		}
+
+		rule := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(dir)}
+		_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, fd, unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0)
+		unix.Close(dir)
+		if errno != 0 {
+			return fmt.Errorf("Landlock rule for %s: %w", path, errno)
+		}
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("Landlock: %w", errno)
+	}
+	return nil
+}
+
+// dropCapabilities empties the bounding, ambient and process capability
+// sets, so not even a root command keeps privileges
+func dropCapabilities() error {
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return fmt.Errorf("clearing ambient capabilities: %w", err)
+	}
+
+	for c := 0; c <= unix.CAP_LAST_CAP; c++ {
+		// EINVAL means the kernel does not know this capability
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil && err != unix.EINVAL {
+			return fmt.Errorf("dropping bounding set: %w", err)
+		}
+	}
+
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	data := [2]unix.CapUserData{}
+	if err := unix.Capset(&hdr, &data[0]); err != nil {
+		return fmt.Errorf("clearing capabilities: %w", err)
+	}
+	return nil
+}
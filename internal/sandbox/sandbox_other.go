@@ -0,0 +1,25 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+	"runtime"
+)
+
+var errUnsupported = errors.New("sandboxing is only supported on Linux, not " + runtime.GOOS)
+
+// Command reports that no restriction is available. In strict mode it
+// fails, otherwise the command runs unrestricted.
+func Command(cfg *Config, argv []string, warnings io.Writer) (*exec.Cmd, error) {
+	if err := report(cfg, warnings, "sandbox", errUnsupported); err != nil {
+		return nil, err
+	}
+	return exec.Command(argv[0], argv[1:]...), nil
+}
+
+func enter(cfg *Config, argv []string) error {
+	return errUnsupported
+}
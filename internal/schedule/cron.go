@@ -0,0 +1,200 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed five-field cron expression (minute hour day month weekday)
+type Cron struct {
+	minute  uint64
+	hour    uint64
+	day     uint64
+	month   uint64
+	weekday uint64
+
+	// Standard cron semantics: when both day fields are restricted a time
+	// matches if either of them does
+	dayRestricted     bool
+	weekdayRestricted bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	aliases  map[string]int
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	dayField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, aliases: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	weekdayField = cronField{name: "day of week", min: 0, max: 7, aliases: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a cron expression such as "0 9 * * 1-5" or "@daily"
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := descriptors[strings.ToLower(expr)]; ok {
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	c := &Cron{}
+	var err error
+	if c.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if c.day, err = parseField(fields[2], dayField); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if c.weekday, err = parseField(fields[4], weekdayField); err != nil {
+		return nil, err
+	}
+
+	// 7 is an alias for Sunday
+	if c.weekday&(1<<7) != 0 {
+		c.weekday = c.weekday&^(1<<7) | 1
+	}
+
+	// As in standard cron, a field starting with "*" such as "*/2" is not
+	// a restriction
+	c.dayRestricted = !strings.HasPrefix(fields[2], "*")
+	c.weekdayRestricted = !strings.HasPrefix(fields[4], "*")
+
+	return c, nil
+}
+
+// parseField turns one comma-separated cron field into a bitmask
+func parseField(field string, f cronField) (uint64, error) {
+	var mask uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field: %q", f.name, part)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(bounds[1]); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range in %s field: %q", f.name, part)
+			}
+		default:
+			v, err := f.value(rangePart)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// "5/15" means starting at 5 through the end of the range
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+
+	return mask, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.aliases[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value in %s field: %q (must be %d-%d)", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Matches reports whether t falls on a minute selected by the expression
+func (c *Cron) Matches(t time.Time) bool {
+	return c.minute&(1<<uint(t.Minute())) != 0 &&
+		c.hour&(1<<uint(t.Hour())) != 0 &&
+		c.month&(1<<uint(t.Month())) != 0 &&
+		c.dayMatches(t)
+}
+
+// dayMatches applies the day-of-month and day-of-week fields to t
+func (c *Cron) dayMatches(t time.Time) bool {
+	dayMatch := c.day&(1<<uint(t.Day())) != 0
+	weekdayMatch := c.weekday&(1<<uint(t.Weekday())) != 0
+
+	if c.dayRestricted && c.weekdayRestricted {
+		return dayMatch || weekdayMatch
+	}
+	return dayMatch && weekdayMatch
+}
+
+// Next returns the first minute strictly after t that matches the expression.
+// It gives up after five years, which only happens for impossible dates
+// such as "0 0 31 2 *", and returns the zero time in that case.
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
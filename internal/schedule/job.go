@@ -0,0 +1,135 @@
+package schedule
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/filelock"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Job is a snip scheduled to run at the times selected by its cron expression
+type Job struct {
+	ID      string    `yaml:"id"`
+	Snip    string    `yaml:"snip"`
+	Args    []string  `yaml:"args,omitempty"`
+	Cron    string    `yaml:"cron"`
+	Created time.Time `yaml:"created"`
+
+	// Command is the interpolated command approved when the job was added.
+	// The daemon refuses to run a risky command that no longer matches it.
+	Command  string `yaml:"command"`
+	Approved bool   `yaml:"approved,omitempty"`
+
+	// Profile is the profile the job was scheduled with, whose variables
+	// and safeguards apply to every run
+	Profile string `yaml:"profile,omitempty"`
+}
+
+// jobsFile is where scheduled jobs are stored, relative to the config dir
+const jobsFile = "schedules.yaml"
+
+// lockFile serializes changes to the jobs, relative to the config dir
+const lockFile = "schedules.lock"
+
+// LogDir returns the directory holding scheduled run logs
+func LogDir(configDir string) string {
+	return filepath.Join(configDir, "schedule", "logs")
+}
+
+// LogPath returns the log file for a job
+func LogPath(configDir, id string) string {
+	return filepath.Join(LogDir(configDir), id+".log")
+}
+
+// NewID returns a short random identifier for a job
+func NewID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return hex.EncodeToString(b)
+}
+
+// LoadJobs reads all scheduled jobs from the config dir
+func LoadJobs(configDir string) ([]*Job, error) {
+	data, err := os.ReadFile(filepath.Join(configDir, jobsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedules: %w", err)
+	}
+
+	var jobs []*Job
+	if err := yaml.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse schedules: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// SaveJobs writes all scheduled jobs to the config dir. The file is
+// replaced in one step, so the daemon never reads half of it.
+func SaveJobs(configDir string, jobs []*Job) error {
+	data, err := yaml.Marshal(jobs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedules: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(configDir, jobsFile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write schedules: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(configDir, jobsFile))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write schedules: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateJobs loads the jobs, lets update change them and saves the result,
+// holding a lock so that concurrent changes are not lost
+func UpdateJobs(configDir string, update func(jobs []*Job) ([]*Job, error)) error {
+	unlock, err := filelock.Acquire(filepath.Join(configDir, lockFile), "schedules")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	jobs, err := LoadJobs(configDir)
+	if err != nil {
+		return err
+	}
+	jobs, err = update(jobs)
+	if err != nil {
+		return err
+	}
+	return SaveJobs(configDir, jobs)
+}
+
+// FindJob returns the job with the given ID
+func FindJob(jobs []*Job, id string) (*Job, error) {
+	for _, job := range jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return nil, fmt.Errorf("schedule '%s' not found", id)
+}
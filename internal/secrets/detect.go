@@ -0,0 +1,134 @@
+package secrets
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Match is a likely secret found in a text
+type Match struct {
+	Kind  string
+	Value string
+	Start int
+	End   int
+	// EnvName is a conventional environment variable for this kind of
+	// secret, used as the default when converting it
+	EnvName string
+}
+
+// Masked shows just enough of the value to recognise it
+func (m Match) Masked() string {
+	return Mask(m.Value)
+}
+
+// Mask hides all but the first four and last two characters of a value
+func Mask(value string) string {
+	if len(value) <= 8 {
+		return strings.Repeat("*", len(value))
+	}
+	return value[:4] + "…" + value[len(value)-2:]
+}
+
+type pattern struct {
+	kind    string
+	envName string
+	re      *regexp.Regexp
+	// group is the submatch holding the secret, 0 for the whole match
+	group int
+}
+
+var patterns = []pattern{
+	{"private key", "PRIVATE_KEY", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`), 0},
+	{"AWS access key", "AWS_ACCESS_KEY_ID", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), 0},
+	{"AWS secret key", "AWS_SECRET_ACCESS_KEY", regexp.MustCompile(`(?i)aws_secret_access_key\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`), 1},
+	{"GitHub token", "GITHUB_TOKEN", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{40,})\b`), 0},
+	{"GitLab token", "GITLAB_TOKEN", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`), 0},
+	{"Slack token", "SLACK_TOKEN", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`), 0},
+	{"Google API key", "GOOGLE_API_KEY", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`), 0},
+	{"Stripe key", "STRIPE_API_KEY", regexp.MustCompile(`\b[sr]k_live_[0-9A-Za-z]{20,}\b`), 0},
+	{"JWT", "TOKEN", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`), 0},
+	{"bearer token", "TOKEN", regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{20,}=*)`), 1},
+	{"password", "PASSWORD", regexp.MustCompile(`(?i)(?:password|passwd|pwd|secret|api_?key|token)\s*[=:]\s*["']?([^\s"'$&;|{}]{6,})`), 1},
+}
+
+// words are candidates for the entropy check
+var words = regexp.MustCompile(`[A-Za-z0-9+/=_-]{20,}`)
+
+// Scan returns the likely secrets in a text, ordered by position. Snip
+// placeholders and variable references are never reported.
+func Scan(text string) []Match {
+	var matches []Match
+	taken := func(start, end int) bool {
+		for _, m := range matches {
+			if start < m.End && end > m.Start {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, p := range patterns {
+		for _, loc := range p.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[2*p.group], loc[2*p.group+1]
+			if start < 0 || taken(start, end) {
+				continue
+			}
+			matches = append(matches, Match{Kind: p.kind, Value: text[start:end], Start: start, End: end, EnvName: p.envName})
+		}
+	}
+
+	for _, loc := range words.FindAllStringIndex(text, -1) {
+		word := text[loc[0]:loc[1]]
+		if taken(loc[0], loc[1]) || !highEntropy(word) {
+			continue
+		}
+		matches = append(matches, Match{Kind: "high-entropy string", Value: word, Start: loc[0], End: loc[1], EnvName: "SECRET"})
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+	return matches
+}
+
+// highEntropy reports whether a word looks random rather than like a path,
+// identifier or placeholder
+func highEntropy(word string) bool {
+	if strings.Contains(word, "{{") {
+		return false
+	}
+
+	var lower, upper, digit bool
+	for _, r := range word {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+	// Random tokens mix character classes, long names usually do not
+	if !digit || !(lower || upper) {
+		return false
+	}
+
+	return entropy(word) >= 4.0
+}
+
+// entropy is the Shannon entropy of a string in bits per character
+func entropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+
+	var h float64
+	n := float64(len(s))
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
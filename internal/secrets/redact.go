@@ -0,0 +1,93 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secret values in text
+const Redacted = "****"
+
+// MinLength is the length of the shortest secret value accepted. Shorter
+// values would mangle every line they happen to appear in when redacted,
+// so they are refused rather than written to logs.
+const MinLength = 4
+
+// CheckLength fails for a value too short to be redacted reliably
+func CheckLength(value string) error {
+	if value != "" && len(value) < MinLength {
+		return fmt.Errorf("secret values must be at least %d characters long", MinLength)
+	}
+	return nil
+}
+
+// Redact replaces every occurrence of the given values in text
+func Redact(text string, values []string) string {
+	for _, v := range redactable(values) {
+		text = strings.ReplaceAll(text, v, Redacted)
+	}
+	return text
+}
+
+// redactable drops empty values and orders the rest longest first, so a
+// value containing another is replaced as a whole
+func redactable(values []string) []string {
+	var kept []string
+	for _, v := range values {
+		if v != "" {
+			kept = append(kept, v)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return len(kept[i]) > len(kept[j]) })
+	return kept
+}
+
+// Redactor is a writer that redacts secret values line by line, so values
+// split across writes are still caught. Call Flush to write a final
+// partial line.
+type Redactor struct {
+	w      io.Writer
+	values []string
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// NewRedactor returns a writer that redacts values before writing to w
+func NewRedactor(w io.Writer, values []string) *Redactor {
+	return &Redactor{w: w, values: redactable(values)}
+}
+
+func (r *Redactor) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.buf.Write(p)
+	i := bytes.LastIndexByte(r.buf.Bytes(), '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+
+	lines := string(r.buf.Next(i + 1))
+	if _, err := io.WriteString(r.w, Redact(lines, r.values)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes what is left of an unterminated line
+func (r *Redactor) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.buf.Len() == 0 {
+		return nil
+	}
+	_, err := io.WriteString(r.w, Redact(r.buf.String(), r.values))
+	r.buf.Reset()
+	return err
+}
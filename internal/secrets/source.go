@@ -0,0 +1,119 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Resolver reads values from secret sources. A source is a reference of
+// the form:
+//
+//	env:NAME       an environment variable
+//	file:PATH      the first line of a file
+//	cmd:COMMAND    the first line a command prints, e.g. "cmd:pass show api"
+//	pass:NAME      shorthand for "cmd:pass show NAME"
+//	vault:NAME     a secret stored with 'sniprun secret set'
+type Resolver struct {
+	// Vault returns the secrets of the encrypted vault. It is only called
+	// for vault: references, so the passphrase is not asked for otherwise.
+	Vault func() (map[string]string, error)
+}
+
+// Resolve returns the value a source reference points to
+func (r *Resolver) Resolve(ref string) (string, error) {
+	scheme, name, ok := strings.Cut(ref, ":")
+	if !ok || name == "" {
+		return "", fmt.Errorf("invalid secret source '%s' (use env:, file:, cmd:, pass: or vault:)", ref)
+	}
+
+	switch scheme {
+	case "env":
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+
+	case "file":
+		if strings.HasPrefix(name, "~/") {
+			home, _ := os.UserHomeDir()
+			name = home + name[1:]
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return firstLine(data), nil
+
+	case "cmd":
+		return runSource(name)
+
+	case "pass":
+		return runSource("pass show " + quote(name))
+
+	case "vault":
+		if r.Vault == nil {
+			return "", fmt.Errorf("no vault is available")
+		}
+		values, err := r.Vault()
+		if err != nil {
+			return "", err
+		}
+		value, ok := values[name]
+		if !ok {
+			return "", fmt.Errorf("secret '%s' is not in the vault", name)
+		}
+		return value, nil
+	}
+
+	return "", fmt.Errorf("unknown secret source '%s' (use env:, file:, cmd:, pass: or vault:)", scheme)
+}
+
+// Local reports whether a source reads files or runs commands on this
+// machine. Only snips written by the user may use such sources, since they
+// are read before the snip's command is checked.
+func Local(ref string) bool {
+	scheme, _, _ := strings.Cut(ref, ":")
+	switch scheme {
+	case "file", "cmd", "pass":
+		return true
+	}
+	return false
+}
+
+// quote makes a name a single word for the shell runSource uses
+func quote(name string) string {
+	if runtime.GOOS == "windows" {
+		return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+	}
+	return "'" + strings.ReplaceAll(name, "'", `'\''`) + "'"
+}
+
+// runSource runs a password manager command and returns the first line of
+// its output. Its errors are shown as they would be in a terminal.
+func runSource(command string) (string, error) {
+	argv := []string{"sh", "-c", command}
+	if runtime.GOOS == "windows" {
+		argv = []string{"powershell", "-Command", command}
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("secret command '%s' failed: %w", command, err)
+	}
+	return firstLine(out), nil
+}
+
+func firstLine(data []byte) string {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+	return strings.TrimRight(string(data), "\r")
+}
@@ -0,0 +1,492 @@
+package security
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Span locates the part of a command a finding refers to, as byte offsets
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Text returns the part of command covered by the span
+func (s Span) Text(command string) string {
+	if s.Start < 0 || s.End > len(command) || s.Start >= s.End {
+		return ""
+	}
+	return command[s.Start:s.End]
+}
+
+// Finding is a local analyzer rule that matched part of a command
+type Finding struct {
+	RuleID  string    `json:"rule_id"`
+	Level   RiskLevel `json:"level"`
+	Message string    `json:"message"`
+	Span    Span      `json:"span"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s", f.RuleID, f.Message)
+}
+
+// shells are interpreters that execute whatever they read on stdin
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true,
+	"python": true, "python3": true, "perl": true, "ruby": true, "node": true,
+}
+
+// downloaders fetch remote content
+var downloaders = map[string]bool{
+	"curl": true, "wget": true, "fetch": true,
+}
+
+// wrappers run the command given in their arguments. The options that take
+// a value are listed in valueOptions.
+var wrappers = map[string]bool{
+	"sudo": true, "doas": true, "env": true, "nohup": true, "exec": true,
+	"command": true, "time": true, "nice": true, "ionice": true, "xargs": true,
+	"timeout": true, "stdbuf": true, "setsid": true,
+}
+
+// credentialPaths are files that hold secrets worth stealing
+var credentialPaths = []string{
+	".ssh/id_", ".aws/credentials", ".netrc", ".docker/config.json", ".kube/config",
+	".gnupg/", ".git-credentials", ".pgpass", "/etc/shadow", "/etc/gshadow",
+}
+
+// blockDevices are path prefixes of raw disks
+var blockDevices = []string{
+	"/dev/sd", "/dev/hd", "/dev/nvme", "/dev/disk", "/dev/mmcblk", "/dev/xvd", "/dev/vd",
+}
+
+// Analyze parses a command with a POSIX/Bash shell parser and reports every
+// known-dangerous pattern it contains
+func Analyze(command string) ([]Finding, error) {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil, err
+	}
+
+	a := &analysis{command: command, seen: make(map[string]bool)}
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.CallExpr:
+			a.checkCall(n)
+		case *syntax.BinaryCmd:
+			if n.Op == syntax.Pipe || n.Op == syntax.PipeAll {
+				a.checkPipeline(n)
+			}
+		case *syntax.Redirect:
+			a.checkRedirect(n)
+		case *syntax.FuncDecl:
+			a.checkForkBomb(n)
+		}
+		return true
+	})
+
+	a.checkExfiltration(file)
+	return a.findings, nil
+}
+
+// CommandNames returns the names of all simple commands in a command line,
+// looking through wrappers such as sudo
+func CommandNames(command string) ([]string, error) {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if expr, ok := node.(*syntax.CallExpr); ok {
+			if c := parseCall(expr); c != nil {
+				names = append(names, c.name)
+			}
+		}
+		return true
+	})
+	return names, nil
+}
+
+type analysis struct {
+	command  string
+	findings []Finding
+	seen     map[string]bool
+}
+
+func (a *analysis) add(id string, level RiskLevel, node syntax.Node, message string) {
+	span := Span{Start: int(node.Pos().Offset()), End: int(node.End().Offset())}
+
+	key := fmt.Sprintf("%s:%d:%d", id, span.Start, span.End)
+	if a.seen[key] {
+		return
+	}
+	a.seen[key] = true
+
+	a.findings = append(a.findings, Finding{RuleID: id, Level: level, Message: message, Span: span})
+}
+
+// call is a simple command with wrappers such as sudo stripped
+type call struct {
+	name string
+	args []string
+	expr *syntax.CallExpr
+}
+
+func parseCall(expr *syntax.CallExpr) *call {
+	words := make([]string, len(expr.Args))
+	for i, w := range expr.Args {
+		words[i] = wordString(w)
+	}
+
+	_, words = unwrap(words)
+	if len(words) == 0 {
+		return nil
+	}
+	return &call{name: path.Base(words[0]), args: words[1:], expr: expr}
+}
+
+// unwrap skips wrappers, their options and option values, e.g.
+// "sudo -u root env FOO=1 rm ...", returning the wrapper names and the
+// words of the wrapped command
+func unwrap(words []string) ([]string, []string) {
+	var names []string
+	for len(words) > 0 {
+		name := path.Base(words[0])
+		if !wrappers[name] {
+			break
+		}
+		names = append(names, name)
+		words = words[1:]
+		for len(words) > 0 {
+			if words[0] == "--" {
+				words = words[1:]
+				break
+			}
+			n := optionWords(name, words)
+			if n == 0 && strings.Contains(words[0], "=") {
+				n = 1
+			}
+			if n == 0 {
+				break
+			}
+			words = words[n:]
+		}
+		// timeout takes the duration before the command
+		if name == "timeout" && len(words) > 0 {
+			words = words[1:]
+		}
+	}
+	return names, words
+}
+
+// wordString renders a shell word with quotes removed and expansions kept
+// in their source form, e.g. "$HOME"
+func wordString(w *syntax.Word) string {
+	var b strings.Builder
+	for _, part := range w.Parts {
+		writeWordPart(&b, part)
+	}
+	return b.String()
+}
+
+func writeWordPart(b *strings.Builder, part syntax.WordPart) {
+	switch p := part.(type) {
+	case *syntax.Lit:
+		b.WriteString(p.Value)
+	case *syntax.SglQuoted:
+		b.WriteString(p.Value)
+	case *syntax.DblQuoted:
+		for _, inner := range p.Parts {
+			writeWordPart(b, inner)
+		}
+	case *syntax.ParamExp:
+		b.WriteString("$" + p.Param.Value)
+	case *syntax.CmdSubst:
+		b.WriteString("$(...)")
+	case *syntax.ProcSubst:
+		b.WriteString("<(...)")
+	}
+}
+
+// worldWritable reports whether a chmod mode lets everyone write, e.g.
+// 777, 0777, 0666, o+w or a=rwx
+func worldWritable(mode string) bool {
+	if mode != "" && len(mode) <= 4 && strings.Trim(mode, "01234567") == "" {
+		var bits int
+		fmt.Sscanf(mode, "%o", &bits)
+		return bits&0o002 != 0
+	}
+
+	for _, clause := range strings.Split(mode, ",") {
+		who := strings.TrimLeft(clause, "ugoa")
+		if len(who) < 2 || (who[0] != '+' && who[0] != '=') || !strings.Contains(who[1:], "w") {
+			continue
+		}
+		if strings.ContainsAny(clause[:len(clause)-len(who)], "oa") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFlag reports whether args contain a short flag letter or a long flag
+func hasFlag(args []string, short byte, long string) bool {
+	for _, arg := range args {
+		if arg == "--"+long {
+			return true
+		}
+		if len(arg) > 1 && arg[0] == '-' && arg[1] != '-' && strings.IndexByte(arg[1:], short) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func isCriticalPath(p string) bool {
+	p = strings.TrimRight(p, "/*")
+	switch p {
+	case "", "~", "$HOME", "/home", "/root", "/usr", "/etc", "/var", "/boot", "/bin", "/lib":
+		return true
+	}
+	return false
+}
+
+func isBlockDevice(p string) bool {
+	for _, prefix := range blockDevices {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCredentialPath(p string) bool {
+	for _, c := range credentialPaths {
+		if strings.Contains(p, c) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *analysis) checkCall(expr *syntax.CallExpr) {
+	c := parseCall(expr)
+	if c == nil {
+		return
+	}
+
+	switch c.name {
+	case "rm":
+		recursive := hasFlag(c.args, 'r', "recursive") || hasFlag(c.args, 'R', "recursive")
+		for _, arg := range c.args {
+			if recursive && !strings.HasPrefix(arg, "-") && isCriticalPath(arg) {
+				a.add("rm-root", RiskDangerous, expr, fmt.Sprintf("Recursively deletes %s", arg))
+				return
+			}
+		}
+		if recursive {
+			a.add("rm-recursive", RiskWarning, expr, "Deletes files recursively")
+		}
+
+	case "dd":
+		for _, arg := range c.args {
+			if strings.HasPrefix(arg, "of=") && isBlockDevice(strings.TrimPrefix(arg, "of=")) {
+				a.add("dd-block-device", RiskDangerous, expr, fmt.Sprintf("Overwrites block device %s", strings.TrimPrefix(arg, "of=")))
+			}
+		}
+
+	case "chmod":
+		if hasFlag(c.args, 'R', "recursive") {
+			for _, arg := range c.args {
+				if worldWritable(arg) {
+					a.add("chmod-777-recursive", RiskWarning, expr, "Makes files world-writable recursively")
+				}
+			}
+		}
+
+	case "mkfs", "mkfs.ext4", "mkfs.ext3", "mkfs.xfs", "mkfs.btrfs", "mkfs.vfat", "mkfs.fat", "mkfs.ntfs":
+		a.add("mkfs", RiskWarning, expr, "Formats a filesystem")
+
+	case "tee", "cp", "mv", "install", "ln":
+		// The destination is the last argument, tee writes to all of them
+		for i, arg := range c.args {
+			if strings.HasPrefix(arg, "/etc/") && (c.name == "tee" || i == len(c.args)-1) {
+				a.add("write-etc", RiskWarning, expr, fmt.Sprintf("Modifies system configuration in %s", arg))
+			}
+		}
+
+	case "sed":
+		if hasFlag(c.args, 'i', "in-place") {
+			for _, arg := range c.args {
+				if strings.HasPrefix(arg, "/etc/") {
+					a.add("write-etc", RiskWarning, expr, fmt.Sprintf("Modifies system configuration in %s", arg))
+				}
+			}
+		}
+
+	case "eval", "sh", "bash", "zsh", "dash":
+		// eval "$(curl ...)" and bash <(curl ...) run code without a pipe
+		for _, w := range expr.Args[1:] {
+			if containsCall(w, func(c *call) bool { return downloaders[c.name] }) {
+				a.add("remote-exec", RiskDangerous, expr, "Executes code downloaded from the network")
+			}
+			if containsCall(w, isBase64Decode) {
+				a.add("base64-exec", RiskDangerous, expr, "Executes base64-decoded code")
+			}
+		}
+	}
+
+	for _, arg := range c.args {
+		if isCredentialPath(arg) {
+			a.add("credential-read", RiskWarning, expr, fmt.Sprintf("Accesses credential file %s", arg))
+		}
+	}
+}
+
+func (a *analysis) checkPipeline(bc *syntax.BinaryCmd) {
+	stages := flattenPipeline(bc)
+
+	for i := 1; i < len(stages); i++ {
+		c := firstCall(stages[i])
+		if c == nil || !shells[c.name] {
+			continue
+		}
+
+		for _, earlier := range stages[:i] {
+			if containsCall(earlier, func(c *call) bool { return downloaders[c.name] }) {
+				a.add("remote-exec", RiskDangerous, bc, fmt.Sprintf("Pipes downloaded content into %s", c.name))
+			}
+			if containsCall(earlier, isBase64Decode) {
+				a.add("base64-exec", RiskDangerous, bc, fmt.Sprintf("Pipes base64-decoded content into %s", c.name))
+			}
+		}
+	}
+}
+
+func (a *analysis) checkRedirect(r *syntax.Redirect) {
+	if r.Word == nil {
+		return
+	}
+	target := wordString(r.Word)
+
+	switch r.Op {
+	case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll:
+		if isBlockDevice(target) {
+			a.add("dd-block-device", RiskDangerous, r, fmt.Sprintf("Overwrites block device %s", target))
+		}
+		if strings.HasPrefix(target, "/etc/") {
+			a.add("write-etc", RiskWarning, r, fmt.Sprintf("Modifies system configuration in %s", target))
+		}
+	case syntax.RdrIn:
+		if isCredentialPath(target) {
+			a.add("credential-read", RiskWarning, r, fmt.Sprintf("Accesses credential file %s", target))
+		}
+	}
+}
+
+// checkForkBomb flags functions that pipe into themselves in the background,
+// the shape of the classic :(){ :|:& };:
+func (a *analysis) checkForkBomb(fn *syntax.FuncDecl) {
+	name := fn.Name.Value
+	selfCall := func(c *call) bool { return c.name == name }
+
+	syntax.Walk(fn.Body, func(node syntax.Node) bool {
+		if bc, ok := node.(*syntax.BinaryCmd); ok && (bc.Op == syntax.Pipe || bc.Op == syntax.PipeAll) {
+			if containsCall(bc.X, selfCall) && containsCall(bc.Y, selfCall) {
+				a.add("fork-bomb", RiskDangerous, fn, "Fork bomb: function recursively spawns copies of itself")
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// checkExfiltration escalates credential reads in commands that also talk to
+// the network
+func (a *analysis) checkExfiltration(file *syntax.File) {
+	readsCredentials := false
+	for _, f := range a.findings {
+		if f.RuleID == "credential-read" {
+			readsCredentials = true
+		}
+	}
+
+	network := map[string]bool{"nc": true, "ncat": true, "scp": true, "ssh": true, "rsync": true}
+	if readsCredentials && containsCall(file, func(c *call) bool { return downloaders[c.name] || network[c.name] }) {
+		a.add("credential-exfiltration", RiskDangerous, file, "Reads credential files and sends data over the network")
+	}
+}
+
+func isBase64Decode(c *call) bool {
+	switch c.name {
+	case "base64", "base32":
+		return hasFlag(c.args, 'd', "decode") || hasFlag(c.args, 'D', "decode")
+	case "openssl":
+		return len(c.args) > 0 && (c.args[0] == "base64" || c.args[0] == "enc") && hasFlag(c.args, 'd', "decode")
+	case "xxd":
+		return hasFlag(c.args, 'r', "revert")
+	}
+	return false
+}
+
+// containsCall reports whether any command inside node satisfies pred
+func containsCall(node syntax.Node, pred func(*call) bool) bool {
+	found := false
+	syntax.Walk(node, func(n syntax.Node) bool {
+		if found {
+			return false
+		}
+		if expr, ok := n.(*syntax.CallExpr); ok {
+			if c := parseCall(expr); c != nil && pred(c) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// firstCall returns the first simple command in a statement
+func firstCall(stmt *syntax.Stmt) *call {
+	var first *call
+	syntax.Walk(stmt, func(n syntax.Node) bool {
+		if first != nil {
+			return false
+		}
+		if expr, ok := n.(*syntax.CallExpr); ok {
+			first = parseCall(expr)
+		}
+		return first == nil
+	})
+	return first
+}
+
+// flattenPipeline turns nested "a | b | c" commands into their stages
+func flattenPipeline(bc *syntax.BinaryCmd) []*syntax.Stmt {
+	var stages []*syntax.Stmt
+	for _, stmt := range []*syntax.Stmt{bc.X, bc.Y} {
+		if inner, ok := stmt.Cmd.(*syntax.BinaryCmd); ok && (inner.Op == syntax.Pipe || inner.Op == syntax.PipeAll) {
+			stages = append(stages, flattenPipeline(inner)...)
+		} else {
+			stages = append(stages, stmt)
+		}
+	}
+	return stages
+}
+
+// parseFailure describes a command the analyzer could not parse. Commands
+// run through sh elsewhere would fail anyway, so the warning is harmless;
+// Windows snips are PowerShell and are not expected to parse.
+func parseFailure(err error) (RiskLevel, string) {
+	if runtime.GOOS == "windows" {
+		return RiskSafe, "Not a POSIX shell command, local rules skipped"
+	}
+	return RiskWarning, fmt.Sprintf("[parse-error] Command could not be parsed: %v", err)
+}
@@ -0,0 +1,197 @@
+package security
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CacheDir returns the directory holding cached verdicts
+func CacheDir(configDir string) string {
+	return filepath.Join(configDir, "cache", "verdicts")
+}
+
+// Identity returns a string that changes whenever a validator's verdicts
+// could change, such as a different model. Validators without an Identity
+// method are identified by their name.
+func Identity(v Validator) string {
+	if i, ok := v.(interface{ Identity() string }); ok {
+		return i.Identity()
+	}
+	return v.Name()
+}
+
+// IsRemote reports whether a validator calls a network service, so callers
+// can limit how often it is used. Validators without a Remote method are
+// assumed to be local.
+func IsRemote(v Validator) bool {
+	if r, ok := v.(interface{ Remote() bool }); ok {
+		return r.Remote()
+	}
+	return false
+}
+
+// CachedValidator stores verdicts of another validator on disk. Fresh
+// verdicts are reused without calling the validator, and when it fails any
+// previous verdict is used regardless of its age.
+type CachedValidator struct {
+	Validator     Validator
+	Dir           string
+	TTL           time.Duration
+	PolicyVersion string
+
+	now func() time.Time
+}
+
+// NewCache caches the verdicts of a validator under dir
+func NewCache(v Validator, dir string, ttl time.Duration, policyVersion string) *CachedValidator {
+	return &CachedValidator{Validator: v, Dir: dir, TTL: ttl, PolicyVersion: policyVersion, now: time.Now}
+}
+
+func (c *CachedValidator) Name() string {
+	return c.Validator.Name()
+}
+
+func (c *CachedValidator) Identity() string {
+	return Identity(c.Validator)
+}
+
+func (c *CachedValidator) Remote() bool {
+	return IsRemote(c.Validator)
+}
+
+// cacheEntry is a verdict as stored on disk. The command itself is not
+// stored, only its hash in the file name.
+type cacheEntry struct {
+	RiskLevel RiskLevel `json:"risk_level"`
+	Reason    string    `json:"reason"`
+	Validator string    `json:"validator"`
+	Findings  []Finding `json:"findings,omitempty"`
+	Verdicts  []Verdict `json:"verdicts,omitempty"`
+	Created   time.Time `json:"created"`
+}
+
+func (c *CachedValidator) Validate(req *Request) (*ValidationResult, error) {
+	path := filepath.Join(c.Dir, c.key(req)+".json")
+	entry := readCacheEntry(path)
+
+	if entry != nil && c.now().Sub(entry.Created) < c.TTL {
+		return entry.result(""), nil
+	}
+
+	result, err := c.Validator.Validate(req)
+	if err != nil {
+		if entry != nil {
+			note := fmt.Sprintf(" (cached verdict from %s, validator unavailable: %v)", entry.Created.Format("2006-01-02 15:04"), err)
+			return entry.result(note), nil
+		}
+		return nil, err
+	}
+
+	// An incomplete verdict, e.g. while offline, is no better than a
+	// previous complete one
+	if result.Transient && entry != nil && entry.RiskLevel.Severity() >= result.RiskLevel.Severity() {
+		return entry.result(fmt.Sprintf(" (cached verdict from %s)", entry.Created.Format("2006-01-02 15:04"))), nil
+	}
+
+	if !result.Transient {
+		// A cache that cannot be written only costs a slower next run
+		writeCacheEntry(path, &cacheEntry{
+			RiskLevel: result.RiskLevel,
+			Reason:    result.Reason,
+			Validator: result.Validator,
+			Findings:  result.Findings,
+			Verdicts:  result.Verdicts,
+			Created:   c.now(),
+		})
+	}
+	return result, nil
+}
+
+// key hashes everything a verdict depends on
+func (c *CachedValidator) key(req *Request) string {
+	h := sha256.New()
+	capabilities := ""
+	if req.Capabilities != nil {
+		capabilities = fmt.Sprintf("%+v", *req.Capabilities)
+	}
+	for _, part := range []string{req.Command, req.Category, req.Trust, capabilities, Identity(c.Validator), c.PolicyVersion} {
+		fmt.Fprintf(h, "%d:%s\n", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (e *cacheEntry) result(note string) *ValidationResult {
+	result := newResult(e.Validator, e.RiskLevel, e.Reason+note)
+	result.Findings = e.Findings
+	result.Verdicts = e.Verdicts
+	return result
+}
+
+func readCacheEntry(path string) *cacheEntry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.RiskLevel.Severity() < 0 {
+		return nil
+	}
+	return &entry
+}
+
+func writeCacheEntry(path string, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+
+	// Write to a temporary file first so concurrent runs never read half
+	// an entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), "*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// ClearCache removes all cached verdicts and returns how many there were
+func ClearCache(configDir string) (int, error) {
+	dir := CacheDir(configDir)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	count := 0
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == ".json" {
+			count++
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return 0, fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,344 @@
+package security
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Capabilities are what a snip declares its command needs, see
+// CheckCapabilities
+type Capabilities struct {
+	Network    bool
+	Writes     []string
+	Reads      []string
+	Privileged bool
+}
+
+// networkCommands always talk to the network
+var networkCommands = map[string]bool{
+	"curl": true, "wget": true, "fetch": true, "nc": true, "ncat": true, "telnet": true,
+	"ssh": true, "scp": true, "sftp": true, "ftp": true, "rsync": true, "ping": true,
+	"dig": true, "nslookup": true, "host": true, "http": true, "gh": true,
+}
+
+// networkSubcommands are subcommands of tools that otherwise work offline
+var networkSubcommands = map[string][]string{
+	"git":     {"clone", "fetch", "pull", "push", "ls-remote"},
+	"apt":     {"install", "update", "upgrade"},
+	"apt-get": {"install", "update", "upgrade"},
+	"dnf":     {"install", "update", "upgrade"},
+	"yum":     {"install", "update", "upgrade"},
+	"brew":    {"install", "update", "upgrade"},
+	"pip":     {"install", "download"},
+	"pip3":    {"install", "download"},
+	"npm":     {"install", "i", "ci", "publish", "update"},
+	"yarn":    {"add", "install"},
+	"cargo":   {"install", "fetch", "publish"},
+	"go":      {"get", "install"},
+	"docker":  {"pull", "push", "login"},
+}
+
+// privilegeCommands run their arguments as another user
+var privilegeCommands = map[string]bool{
+	"sudo": true, "doas": true, "su": true, "pkexec": true, "run0": true,
+}
+
+// CheckCapabilities reports everything a command does beyond its declared
+// capabilities: network use, writes outside the declared paths, reads of
+// credential files that are not declared and privilege escalation. Paths
+// the parser cannot resolve, e.g. "$(mktemp)", are not checked.
+func CheckCapabilities(command string, declared *Capabilities) ([]Finding, error) {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil, err
+	}
+
+	a := &analysis{command: command, seen: make(map[string]bool)}
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.CallExpr:
+			a.checkCallCapabilities(n, declared)
+		case *syntax.Redirect:
+			a.checkRedirectCapabilities(n, declared)
+		}
+		return true
+	})
+	return a.findings, nil
+}
+
+func (a *analysis) checkCallCapabilities(expr *syntax.CallExpr, declared *Capabilities) {
+	if !declared.Privileged {
+		if name := escalation(expr); name != "" {
+			a.add("undeclared-privileged", RiskWarning, expr, fmt.Sprintf("Runs %s without declaring privileged", name))
+		}
+	}
+
+	c := parseCall(expr)
+	if c == nil {
+		return
+	}
+
+	if !declared.Network && usesNetwork(c) {
+		a.add("undeclared-network", RiskWarning, expr, fmt.Sprintf("Uses the network (%s) without declaring network", c.name))
+	}
+
+	for _, target := range writeTargets(c) {
+		if !isDevice(target) && target != "-" && !covered(target, declared.Writes) {
+			a.add("undeclared-write", RiskWarning, expr, fmt.Sprintf("Writes %s, which is not in the declared writes", target))
+		}
+	}
+
+	for _, arg := range c.args {
+		if isCredentialPath(arg) && !covered(arg, declared.Reads) {
+			a.add("undeclared-read", RiskWarning, expr, fmt.Sprintf("Reads %s, which is not in the declared reads", arg))
+		}
+	}
+}
+
+func (a *analysis) checkRedirectCapabilities(r *syntax.Redirect, declared *Capabilities) {
+	if r.Word == nil {
+		return
+	}
+	target := wordString(r.Word)
+
+	switch r.Op {
+	case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll:
+		if !isDevice(target) && !covered(target, declared.Writes) {
+			a.add("undeclared-write", RiskWarning, r, fmt.Sprintf("Writes %s, which is not in the declared writes", target))
+		}
+	case syntax.RdrIn:
+		if isCredentialPath(target) && !covered(target, declared.Reads) {
+			a.add("undeclared-read", RiskWarning, r, fmt.Sprintf("Reads %s, which is not in the declared reads", target))
+		}
+	}
+}
+
+// escalation returns the privilege escalation command a call starts with,
+// looking through wrappers such as env
+func escalation(expr *syntax.CallExpr) string {
+	words := make([]string, len(expr.Args))
+	for i, w := range expr.Args {
+		words[i] = wordString(w)
+	}
+
+	names, rest := unwrap(words)
+	if len(rest) > 0 {
+		names = append(names, path.Base(rest[0]))
+	}
+	for _, name := range names {
+		if privilegeCommands[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+func usesNetwork(c *call) bool {
+	if networkCommands[c.name] {
+		return true
+	}
+	sub := firstOperand(c.name, c.args)
+	for _, s := range networkSubcommands[c.name] {
+		if sub == s {
+			return true
+		}
+	}
+	return false
+}
+
+// writeTargets returns the files a command modifies
+func writeTargets(c *call) []string {
+	operands := operands(c.name, c.args)
+
+	switch c.name {
+	case "touch", "mkdir", "rm", "rmdir", "truncate", "shred", "tee":
+		return operands
+	case "cp", "mv", "install", "ln":
+		// The destination is given with -t or is the last operand
+		if dirs := optionValues(c.args, "-t", "--target-directory"); len(dirs) > 0 {
+			return dirs
+		}
+		if len(operands) >= 2 {
+			return operands[len(operands)-1:]
+		}
+	case "chmod", "chown", "chgrp":
+		// The first operand is the mode or owner
+		if len(operands) >= 2 {
+			return operands[1:]
+		}
+	case "sed":
+		if !hasFlag(c.args, 'i', "in-place") {
+			break
+		}
+		// The script is the first operand unless given with -e or -f
+		if len(optionValues(c.args, "-e", "--expression")) > 0 || len(optionValues(c.args, "-f", "--file")) > 0 {
+			return operands
+		}
+		if len(operands) >= 2 {
+			return operands[1:]
+		}
+	case "dd":
+		for _, arg := range c.args {
+			if strings.HasPrefix(arg, "of=") {
+				return []string{strings.TrimPrefix(arg, "of=")}
+			}
+		}
+	case "curl":
+		return optionValues(c.args, "-o", "--output")
+	case "wget":
+		return optionValues(c.args, "-O", "--output-document")
+	}
+	return nil
+}
+
+// optionValues returns the values given to an option as a separate word
+// or as --long=value
+func optionValues(args []string, short, long string) []string {
+	var values []string
+	for i, arg := range args {
+		switch {
+		case (arg == short || arg == long) && i+1 < len(args):
+			values = append(values, args[i+1])
+		case strings.HasPrefix(arg, long+"="):
+			values = append(values, strings.TrimPrefix(arg, long+"="))
+		}
+	}
+	return values
+}
+
+// valueOptions are the options of file commands and wrappers that take a
+// value, so the value is not mistaken for an operand or the wrapped command,
+// e.g. 755 in "mkdir -m 755 dir" or root in "sudo -u root rm"
+var valueOptions = map[string][]string{
+	"sudo":    {"-u", "--user", "-g", "--group", "-C", "--close-from", "-D", "--chdir", "-h", "--host", "-p", "--prompt", "-r", "--role", "-t", "--type", "-T", "--command-timeout", "-U", "--other-user"},
+	"doas":    {"-u", "-C"},
+	"nice":    {"-n", "--adjustment"},
+	"ionice":  {"-c", "--class", "-n", "--classdata", "-p", "--pid", "-P", "--pgid", "-u", "--uid"},
+	"env":     {"-u", "--unset", "-C", "--chdir", "-S", "--split-string"},
+	"timeout": {"-k", "--kill-after", "-s", "--signal"},
+	"stdbuf":  {"-i", "--input", "-o", "--output", "-e", "--error"},
+	"time":    {"-f", "--format", "-o", "--output"},
+	"xargs":   {"-a", "--arg-file", "-d", "--delimiter", "-E", "-I", "-L", "--max-lines", "-n", "--max-args", "-P", "--max-procs", "-s", "--max-chars"},
+
+	"mkdir":    {"-m", "--mode"},
+	"touch":    {"-d", "--date", "-r", "--reference", "-t"},
+	"truncate": {"-s", "--size", "-r", "--reference"},
+	"shred":    {"-n", "--iterations", "-s", "--size", "--random-source"},
+	"cp":       {"-t", "--target-directory", "-S", "--suffix"},
+	"mv":       {"-t", "--target-directory", "-S", "--suffix"},
+	"ln":       {"-t", "--target-directory", "-S", "--suffix"},
+	"install":  {"-t", "--target-directory", "-S", "--suffix", "-m", "--mode", "-o", "--owner", "-g", "--group"},
+	"chmod":    {"--reference"},
+	"chown":    {"--reference", "--from"},
+	"chgrp":    {"--reference"},
+	"sed":      {"-e", "--expression", "-f", "--file", "-l", "--line-length"},
+}
+
+// takesValue reports whether an option of the named command takes a value
+func takesValue(name, option string) bool {
+	for _, o := range valueOptions[name] {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// optionWords returns how many words the option at args[0] takes up with
+// its value, or 0 if args[0] is not an option
+func optionWords(name string, args []string) int {
+	arg := args[0]
+	switch {
+	case strings.HasPrefix(arg, "--"):
+		if !strings.Contains(arg, "=") && takesValue(name, arg) && len(args) > 1 {
+			return 2
+		}
+		return 1
+	case len(arg) > 1 && arg[0] == '-':
+		// In a group such as -pm the rest of the word is the value, or the
+		// next word if the option letter comes last
+		for j := 1; j < len(arg); j++ {
+			if takesValue(name, "-"+arg[j:j+1]) {
+				if j == len(arg)-1 && len(args) > 1 {
+					return 2
+				}
+				break
+			}
+		}
+		return 1
+	}
+	return 0
+}
+
+// operands returns the arguments of a command that are neither options
+// nor option values. Everything after "--" is an operand.
+func operands(name string, args []string) []string {
+	var result []string
+	for i := 0; i < len(args); {
+		if args[i] == "--" {
+			return append(result, args[i+1:]...)
+		}
+		if n := optionWords(name, args[i:]); n > 0 {
+			i += n
+			continue
+		}
+		result = append(result, args[i])
+		i++
+	}
+	return result
+}
+
+func firstOperand(name string, args []string) string {
+	if ops := operands(name, args); len(ops) > 0 {
+		return ops[0]
+	}
+	return ""
+}
+
+// isDevice reports whether writing a path only reaches a terminal or the
+// bit bucket
+func isDevice(p string) bool {
+	switch p {
+	case "/dev/null", "/dev/stdout", "/dev/stderr", "/dev/tty":
+		return true
+	}
+	return false
+}
+
+// covered reports whether a path is one of the declared paths or below
+// one. "." covers every relative path that stays in the working directory.
+// Paths with unresolved expansions, e.g. "$TMPDIR/out", cannot be checked
+// and count as covered.
+func covered(p string, declared []string) bool {
+	p = normalizePath(p)
+	if strings.Contains(p, "$") || strings.Contains(p, "<(") {
+		return true
+	}
+
+	relative := !strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "~")
+	for _, d := range declared {
+		d = normalizePath(d)
+		if d == "." && relative && p != ".." && !strings.HasPrefix(p, "../") {
+			return true
+		}
+		if p == d || strings.HasPrefix(p, strings.TrimSuffix(d, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePath writes $HOME as ~ and cleans the path so that declared and
+// used paths compare equal
+func normalizePath(p string) string {
+	if p == "$HOME" || strings.HasPrefix(p, "$HOME/") {
+		p = "~" + strings.TrimPrefix(p, "$HOME")
+	}
+	if p == "" {
+		return p
+	}
+	return path.Clean(p)
+}
@@ -0,0 +1,78 @@
+package security
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/mini-page/sniprun/config"
+)
+
+func init() {
+	Register("command", func(cfg *config.Config) (Validator, error) {
+		if len(cfg.ValidatorCommand) == 0 {
+			return nil, fmt.Errorf("the command validator needs validator_command in the config")
+		}
+		return &CommandValidator{Argv: cfg.ValidatorCommand}, nil
+	})
+}
+
+// CommandValidator delegates the check to an external program. The request
+// is written to its stdin as JSON, with the values of secret arguments
+// masked:
+//
+//	{"command": "...", "snip": "...", "category": "...", "trust": "..."}
+//
+// and it must print a JSON verdict on stdout:
+//
+//	{"risk_level": "safe|warning|dangerous", "reason": "..."}
+type CommandValidator struct {
+	Argv []string
+}
+
+func (v *CommandValidator) Name() string {
+	return "command"
+}
+
+func (v *CommandValidator) Identity() string {
+	return "command:" + strings.Join(v.Argv, " ")
+}
+
+func (v *CommandValidator) Validate(req *Request) (*ValidationResult, error) {
+	input, err := json.Marshal(map[string]string{
+		"command":  req.Shared(),
+		"snip":     req.Snip,
+		"category": req.Category,
+		"trust":    req.Trust,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(v.Argv[0], v.Argv[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("validator command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var result struct {
+		RiskLevel string `json:"risk_level"`
+		Reason    string `json:"reason"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse validator command output: %w", err)
+	}
+
+	level := RiskLevel(result.RiskLevel)
+	if level.Severity() < 0 {
+		return nil, fmt.Errorf("validator command returned unknown risk level '%s'", result.RiskLevel)
+	}
+
+	return newResult(v.Name(), level, result.Reason), nil
+}
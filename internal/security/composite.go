@@ -0,0 +1,152 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mini-page/sniprun/config"
+)
+
+func init() {
+	Register("composite", func(cfg *config.Config) (Validator, error) {
+		if len(cfg.Composite) == 0 {
+			return nil, fmt.Errorf("the composite validator needs a composite list in the config")
+		}
+
+		var children []Validator
+		for _, name := range cfg.Composite {
+			if name == "composite" {
+				return nil, fmt.Errorf("the composite validator cannot contain itself")
+			}
+			v, err := New(name, cfg)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, v)
+		}
+
+		composite := NewComposite(children...)
+		if cfg.CompositeQuorum < 0 || cfg.CompositeQuorum > len(children) {
+			return nil, fmt.Errorf("composite_quorum must be between 0 (the highest risk wins) and %d, got %d", len(children), cfg.CompositeQuorum)
+		}
+		composite.Quorum = cfg.CompositeQuorum
+		return composite, nil
+	})
+}
+
+// CompositeValidator runs several validators and merges their verdicts
+type CompositeValidator struct {
+	Validators []Validator
+	// Quorum is how many validators must report a risk level (or a higher
+	// one) for the merged result to reach it. 0 or 1 means the highest risk
+	// wins.
+	Quorum int
+}
+
+// NewComposite combines validators so that the most severe verdict wins
+func NewComposite(validators ...Validator) *CompositeValidator {
+	return &CompositeValidator{Validators: validators}
+}
+
+func (v *CompositeValidator) Name() string {
+	names := make([]string, len(v.Validators))
+	for i, child := range v.Validators {
+		names[i] = child.Name()
+	}
+	return "composite(" + strings.Join(names, ",") + ")"
+}
+
+func (v *CompositeValidator) Identity() string {
+	ids := make([]string, len(v.Validators))
+	for i, child := range v.Validators {
+		ids[i] = Identity(child)
+	}
+	return fmt.Sprintf("composite(%s)/%d", strings.Join(ids, ","), v.Quorum)
+}
+
+func (v *CompositeValidator) Remote() bool {
+	for _, child := range v.Validators {
+		if IsRemote(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate runs every validator and merges the verdicts. Failing validators
+// are skipped as long as at least one of them returns a verdict.
+func (v *CompositeValidator) Validate(req *Request) (*ValidationResult, error) {
+	var results []*ValidationResult
+	var verdicts []Verdict
+	var failures []string
+	transient := false
+
+	for _, child := range v.Validators {
+		result, err := child.Validate(req)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", child.Name(), err))
+			verdicts = append(verdicts, Verdict{Validator: child.Name(), Error: err.Error()})
+			continue
+		}
+		transient = transient || result.Transient
+		results = append(results, result)
+		verdicts = append(verdicts, result.Contributions()...)
+	}
+
+	if len(results) == 0 {
+		if len(failures) == 0 {
+			return nil, fmt.Errorf("no validators configured")
+		}
+		return nil, fmt.Errorf("all validators failed: %s", strings.Join(failures, "; "))
+	}
+
+	level := v.merge(results)
+
+	// The result is attributed to the validators that reported its level
+	var names, reasons []string
+	var findings []Finding
+	for _, r := range results {
+		if r.RiskLevel == level {
+			names = append(names, r.Validator)
+			reasons = append(reasons, r.Reason)
+		}
+		findings = append(findings, r.Findings...)
+	}
+
+	merged := newResult(strings.Join(names, ","), level, strings.Join(reasons, "; "))
+	if len(names) == 0 {
+		merged.Validator = v.Name()
+		merged.Reason = fmt.Sprintf("Not enough validators agree on a higher risk (quorum %d)", v.Quorum)
+	}
+	merged.Findings = findings
+	merged.Verdicts = verdicts
+	// A verdict missing some of its validators is not worth keeping
+	merged.Transient = transient || len(failures) > 0
+	return merged, nil
+}
+
+// merge returns the highest risk level reported by at least a quorum of
+// the results. The quorum shrinks to the number of validators that answered,
+// so an unavailable validator cannot make a risky command look safe.
+func (v *CompositeValidator) merge(results []*ValidationResult) RiskLevel {
+	quorum := v.Quorum
+	if quorum < 1 {
+		quorum = 1
+	}
+	if quorum > len(results) {
+		quorum = len(results)
+	}
+
+	for _, level := range []RiskLevel{RiskDangerous, RiskWarning} {
+		count := 0
+		for _, r := range results {
+			if r.RiskLevel.Severity() >= level.Severity() {
+				count++
+			}
+		}
+		if count >= quorum {
+			return level
+		}
+	}
+	return RiskSafe
+}
@@ -0,0 +1,65 @@
+package security
+
+import (
+	"strings"
+
+	"github.com/mini-page/sniprun/config"
+)
+
+func init() {
+	Register("rules", func(cfg *config.Config) (Validator, error) {
+		return &RulesValidator{}, nil
+	})
+}
+
+// RulesValidator checks commands with the local analyzer without any
+// network access
+type RulesValidator struct{}
+
+func (v *RulesValidator) Name() string {
+	return "rules"
+}
+
+// analyzerVersion changes whenever the analyzer rules change, invalidating
+// cached verdicts
+const analyzerVersion = "2"
+
+func (v *RulesValidator) Identity() string {
+	return "rules:" + analyzerVersion
+}
+
+// Validate reports the most severe finding of the analyzer, with every
+// matched rule listed in the reason. Commands of snips that declare
+// capabilities are also checked against the declaration.
+func (v *RulesValidator) Validate(req *Request) (*ValidationResult, error) {
+	findings, err := Analyze(req.Command)
+	if err != nil {
+		level, reason := parseFailure(err)
+		return newResult(v.Name(), level, reason), nil
+	}
+
+	if req.Capabilities != nil {
+		exceeded, err := CheckCapabilities(req.Command, req.Capabilities)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, exceeded...)
+	}
+
+	if len(findings) == 0 {
+		return newResult(v.Name(), RiskSafe, "No known dangerous patterns found"), nil
+	}
+
+	level := RiskSafe
+	reasons := make([]string, len(findings))
+	for i, f := range findings {
+		if f.Level.Severity() > level.Severity() {
+			level = f.Level
+		}
+		reasons[i] = f.String()
+	}
+
+	result := newResult(v.Name(), level, strings.Join(reasons, "; "))
+	result.Findings = findings
+	return result, nil
+}
@@ -1,142 +1,178 @@
-package security
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"strings"
-)
-
-type RiskLevel string
-
-const (
-	RiskSafe      RiskLevel = "safe"
-	RiskWarning   RiskLevel = "warning"
-	RiskDangerous RiskLevel = "dangerous"
-)
-
-type ValidationResult struct {
-	Safe      bool
-	RiskLevel RiskLevel
-	Reason    string
-}
-
-// ValidateCommand uses Gemini API to check if a command is potentially harmful
-func ValidateCommand(command string) (*ValidationResult, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		// Skip validation if no API key is set
-		return &ValidationResult{
-			Safe:      true,
-			RiskLevel: RiskSafe,
-			Reason:    "Security validation skipped (no API key)",
-		}, nil
-	}
-
-	// Prepare Gemini API request
-	prompt := fmt.Sprintf(`Analyze this shell command for security risks:
-Command: %s
-
-Respond ONLY with JSON in this format:
-{
-  "risk_level": "safe|warning|dangerous",
-  "reason": "brief explanation"
-}
-
-Risk levels:
-- safe: Normal operation, no risk
-- warning: Could be destructive (rm, format, etc.) but legitimate
-- dangerous: Malicious intent detected (exfiltration, malware, etc.)`, command)
-
-	reqBody := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]interface{}{
-					{"text": prompt},
-				},
-			},
-		},
-		"generationConfig": map[string]interface{}{
-			"temperature": 0.1,
-			"maxOutputTokens": 200,
-		},
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Call Gemini API
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-pro:generateContent?key=%s", apiKey)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("API request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response
-	var apiResp struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if len(apiResp.Candidates) == 0 || len(apiResp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("empty response from API")
-	}
-
-	// Extract JSON from response
-	responseText := apiResp.Candidates[0].Content.Parts[0].Text
-	responseText = strings.TrimPrefix(responseText, "```json")
-	responseText = strings.TrimSuffix(responseText, "```")
-	responseText = strings.TrimSpace(responseText)
-
-	var result struct {
-		RiskLevel string `json:"risk_level"`
-		Reason    string `json:"reason"`
-	}
-
-	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse validation result: %w", err)
-	}
-
-	// Convert to ValidationResult
-	vr := &ValidationResult{
-		Safe:      result.RiskLevel == "safe",
-		RiskLevel: RiskLevel(result.RiskLevel),
-		Reason:    result.Reason,
-	}
-
-	return vr, nil
-}
-
-// PromptUserConfirmation asks user to confirm execution of risky commands
-func PromptUserConfirmation(command string, reason string) bool {
-	fmt.Printf("\n⚠️  WARNING: This command may be risky\n")
-	fmt.Printf("Command: %s\n", command)
-	fmt.Printf("Reason: %s\n\n", reason)
-	fmt.Print("Do you want to continue? (yes/no): ")
-
-	var response string
-	fmt.Scanln(&response)
-
-	response = strings.ToLower(strings.TrimSpace(response))
-	return response == "yes" || response == "y"
-}
\ No newline at end of file
+package security
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mini-page/sniprun/config"
+	"github.com/mini-page/sniprun/internal/prompt"
+)
+
+type RiskLevel string
+
+const (
+	RiskSafe      RiskLevel = "safe"
+	RiskWarning   RiskLevel = "warning"
+	RiskDangerous RiskLevel = "dangerous"
+)
+
+// Severity orders risk levels so results can be compared
+func (r RiskLevel) Severity() int {
+	switch r {
+	case RiskSafe:
+		return 0
+	case RiskWarning:
+		return 1
+	case RiskDangerous:
+		return 2
+	default:
+		return -1
+	}
+}
+
+type ValidationResult struct {
+	Safe      bool
+	RiskLevel RiskLevel
+	Reason    string
+	Validator string    // name of the validator that produced the result
+	Findings  []Finding // local analyzer rules that matched, if any
+
+	// Transient marks verdicts that must not be cached, such as fail-closed
+	// outcomes of a validator that could not give a real answer
+	Transient bool
+
+	// Verdicts holds each contributor's verdict when the result combines
+	// several validators
+	Verdicts []Verdict
+}
+
+// Verdict is one validator's contribution to a combined result
+type Verdict struct {
+	Validator string    `json:"validator"`
+	RiskLevel RiskLevel `json:"risk_level,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func (v Verdict) String() string {
+	if v.Error != "" {
+		return fmt.Sprintf("%s: failed: %s", v.Validator, v.Error)
+	}
+	return fmt.Sprintf("%s: %s: %s", v.Validator, v.RiskLevel, v.Reason)
+}
+
+// Explanation lists the reason of every contributor to the result, or the
+// single reason of a result from one validator
+func (r *ValidationResult) Explanation() []string {
+	if len(r.Verdicts) == 0 {
+		return []string{fmt.Sprintf("%s: %s: %s", r.Validator, r.RiskLevel, r.Reason)}
+	}
+
+	lines := make([]string, len(r.Verdicts))
+	for i, v := range r.Verdicts {
+		lines[i] = v.String()
+	}
+	return lines
+}
+
+// Contributions returns the verdicts a result is made of, which is the
+// result itself unless it combines several validators
+func (r *ValidationResult) Contributions() []Verdict {
+	if len(r.Verdicts) > 0 {
+		return r.Verdicts
+	}
+	return []Verdict{{Validator: r.Validator, RiskLevel: r.RiskLevel, Reason: r.Reason}}
+}
+
+// Request describes a command to validate and the snip it came from
+type Request struct {
+	Command  string
+	Snip     string
+	Category string
+	Trust    string
+
+	// Masked is Command with secret values masked, empty when it has none.
+	// Local checks see the real command, anything leaving the process
+	// only the masked one.
+	Masked string
+
+	// Capabilities is nil when the snip does not declare any
+	Capabilities *Capabilities
+}
+
+// Shared returns the command to hand to validators outside the process,
+// such as a language model or an external program
+func (r *Request) Shared() string {
+	if r.Masked != "" {
+		return r.Masked
+	}
+	return r.Command
+}
+
+// Validator checks whether a command is potentially harmful
+type Validator interface {
+	// Name identifies the validator in results and messages
+	Name() string
+	Validate(req *Request) (*ValidationResult, error)
+}
+
+// Factory builds a validator from the user's configuration
+type Factory func(cfg *config.Config) (Validator, error)
+
+var registry = map[string]Factory{}
+
+// Register makes a validator available under a name for the validator
+// setting in the config
+func Register(name string, factory Factory) {
+	registry[name] = factory
+}
+
+// Names returns the registered validator names
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New builds the named validator
+func New(name string, cfg *config.Config) (Validator, error) {
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown validator '%s' (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return factory(cfg)
+}
+
+// FromConfig builds the validator selected in the config
+func FromConfig(cfg *config.Config) (Validator, error) {
+	name := cfg.Validator
+	if name == "" {
+		name = config.DefaultConfig.Validator
+	}
+	return New(name, cfg)
+}
+
+// newResult builds a result for a risk level
+func newResult(validator string, level RiskLevel, reason string) *ValidationResult {
+	return &ValidationResult{
+		Safe:      level == RiskSafe,
+		RiskLevel: level,
+		Reason:    reason,
+		Validator: validator,
+	}
+}
+
+// PromptUserConfirmation asks user to confirm execution of risky commands.
+// Without a terminal it returns an error wrapping prompt.ErrNoInput unless
+// --yes answered it.
+func PromptUserConfirmation(command string, reason string) (bool, error) {
+	fmt.Printf("\n⚠️  WARNING: This command may be risky\n")
+	fmt.Printf("Command: %s\n", command)
+	fmt.Printf("Reason: %s\n\n", reason)
+
+	return prompt.Confirm("Do you want to continue?")
+}
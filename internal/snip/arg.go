@@ -0,0 +1,103 @@
+package snip
+
+import (
+	"fmt"
+
+	"github.com/mini-page/sniprun/internal/secrets"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Arg is a named {{placeholder}} in a snip command.
+//
+// In YAML an argument is usually just its name, but it can also be a mapping
+// to set extra options:
+//
+//	args:
+//	  - branch
+//	  - name: token
+//	    secret: true
+//	    from: env:API_TOKEN
+type Arg struct {
+	Name string `yaml:"name"`
+
+	// Secret values are asked for without echo, masked wherever the command
+	// is shown and never written to the run history
+	Secret bool `yaml:"secret,omitempty"`
+
+	// From is a secret source the value is read from instead of the command
+	// line, see secrets.Resolve
+	From string `yaml:"from,omitempty"`
+}
+
+// UnmarshalYAML accepts either a plain name or a mapping
+func (a *Arg) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		a.Name = value.Value
+		return nil
+	}
+
+	type plain Arg
+	var p plain
+	if err := value.Decode(&p); err != nil {
+		return err
+	}
+	if p.Name == "" {
+		return fmt.Errorf("line %d: argument is missing a name", value.Line)
+	}
+
+	*a = Arg(p)
+	return nil
+}
+
+// MarshalYAML writes arguments without options as a plain name
+func (a Arg) MarshalYAML() (interface{}, error) {
+	if !a.Secret && a.From == "" {
+		return a.Name, nil
+	}
+
+	type plain Arg
+	return plain(a), nil
+}
+
+// ArgNames returns the names of the snip's arguments in order
+func (s *Snip) ArgNames() []string {
+	names := make([]string, len(s.Args))
+	for i, arg := range s.Args {
+		names[i] = arg.Name
+	}
+	return names
+}
+
+// Positions returns the indexes of the arguments given on the command line,
+// which are all those not read from a secret source
+func (s *Snip) Positions() []int {
+	var positions []int
+	for i, arg := range s.Args {
+		if arg.From == "" {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// UsageNames returns the names of the arguments given on the command line
+func (s *Snip) UsageNames() []string {
+	var names []string
+	for _, i := range s.Positions() {
+		names = append(names, s.Args[i].Name)
+	}
+	return names
+}
+
+// MaskArgs returns a copy of args with the values of secret arguments
+// replaced, for showing the command without revealing them
+func (s *Snip) MaskArgs(args []string) []string {
+	masked := append([]string(nil), args...)
+	for i := range masked {
+		if i < len(s.Args) && s.Args[i].Secret {
+			masked[i] = secrets.Redacted
+		}
+	}
+	return masked
+}
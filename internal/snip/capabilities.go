@@ -0,0 +1,68 @@
+package snip
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Capabilities declare what a snip's command needs. Sandboxed runs grant
+// exactly these and the local analyzer flags commands that need more:
+//
+//	capabilities:
+//	  network: true
+//	  writes: [., ~/.cache/tool]
+//	  reads: [~/.aws/credentials]
+//	  privileged: false
+//
+// Relative paths are relative to the working directory. Paths using
+// variables or command substitutions are not checked by the analyzer.
+type Capabilities struct {
+	Network    bool     `yaml:"network,omitempty"`
+	Writes     []string `yaml:"writes,omitempty"`
+	Reads      []string `yaml:"reads,omitempty"`
+	Privileged bool     `yaml:"privileged,omitempty"`
+}
+
+// Summary lists the declared capabilities for display
+func (c *Capabilities) Summary() string {
+	var parts []string
+	if c.Network {
+		parts = append(parts, "network")
+	}
+	if len(c.Writes) > 0 {
+		parts = append(parts, "writes "+strings.Join(c.Writes, ", "))
+	}
+	if len(c.Reads) > 0 {
+		parts = append(parts, "reads "+strings.Join(c.Reads, ", "))
+	}
+	if c.Privileged {
+		parts = append(parts, "privileged")
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// WritablePaths resolves the declared writes against dir, expanding ~ and
+// $HOME
+func (c *Capabilities) WritablePaths(dir string) []string {
+	home, _ := os.UserHomeDir()
+
+	paths := make([]string, 0, len(c.Writes))
+	for _, p := range c.Writes {
+		switch {
+		case p == "~" || p == "$HOME":
+			p = home
+		case strings.HasPrefix(p, "~/"):
+			p = filepath.Join(home, p[2:])
+		case strings.HasPrefix(p, "$HOME/"):
+			p = filepath.Join(home, p[6:])
+		case !filepath.IsAbs(p):
+			p = filepath.Join(dir, p)
+		}
+		paths = append(paths, filepath.Clean(p))
+	}
+	return paths
+}
@@ -0,0 +1,79 @@
+package snip
+
+import (
+	"fmt"
+
+	"github.com/mini-page/sniprun/internal/secrets"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvVar is an environment variable set for the snip command. The value is
+// given in the snip, read from a secret source or asked for when the snip
+// runs:
+//
+//	env:
+//	  - name: REGION
+//	    value: eu-west-1
+//	  - name: API_TOKEN
+//	    secret: true
+//	    from: pass:work/api-token
+type EnvVar struct {
+	Name   string `yaml:"name"`
+	Value  string `yaml:"value,omitempty"`
+	Secret bool   `yaml:"secret,omitempty"`
+	From   string `yaml:"from,omitempty"`
+}
+
+// UnmarshalYAML checks that the variable has a name
+func (e *EnvVar) UnmarshalYAML(value *yaml.Node) error {
+	type plain EnvVar
+	var p plain
+	if err := value.Decode(&p); err != nil {
+		return err
+	}
+	if p.Name == "" {
+		return fmt.Errorf("line %d: environment variable is missing a name", value.Line)
+	}
+	if p.Value != "" && p.From != "" {
+		return fmt.Errorf("line %d: environment variable %s has both a value and a source", value.Line, p.Name)
+	}
+
+	*e = EnvVar(p)
+	return nil
+}
+
+// Display describes where the value of the variable comes from without
+// revealing secrets
+func (e EnvVar) Display() string {
+	switch {
+	case e.From != "":
+		return "from " + e.From
+	case e.Value == "":
+		return "asked for when run"
+	case e.Secret:
+		return secrets.Redacted
+	default:
+		return e.Value
+	}
+}
+
+// CheckSources fails if a snip that was not written by the user reads a
+// value from a file or command. Values are read before the snip is checked
+// and confirmed, so such a source would run its author's code unchecked.
+func (s *Snip) CheckSources() error {
+	if s.Trust == "local" {
+		return nil
+	}
+	for _, a := range s.Args {
+		if secrets.Local(a.From) {
+			return fmt.Errorf("argument '%s': %s snips cannot read values from '%s', only from env: or vault:", a.Name, s.Trust, a.From)
+		}
+	}
+	for _, e := range s.Env {
+		if secrets.Local(e.From) {
+			return fmt.Errorf("environment variable %s: %s snips cannot read values from '%s', only from env: or vault:", e.Name, s.Trust, e.From)
+		}
+	}
+	return nil
+}
@@ -1,50 +1,100 @@
-package snip
-
-import (
-	"fmt"
-	"os"
-	"os/exec"
-	"runtime"
-)
-
-// Execute runs the snip command in a subprocess
-func (s *Snip) Execute(args []string, dryRun bool) error {
-	command, err := s.InterpolateArgs(args)
-	if err != nil {
-		return err
-	}
-
-	if dryRun {
-		fmt.Printf("Would execute: %s\n", command)
-		return nil
-	}
-
-	fmt.Printf("Executing: %s\n", command)
-
-	// Determine shell based on OS
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("powershell", "-Command", command)
-	} else {
-		cmd = exec.Command("sh", "-c", command)
-	}
-
-	// Connect to stdio
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	return cmd.Run()
-}
-
-// ExecuteInShell runs the command and returns output for evaluation in current shell
-// This is for advanced use cases like cd, export, etc.
-func (s *Snip) ExecuteInShell(args []string) (string, error) {
-	command, err := s.InterpolateArgs(args)
-	if err != nil {
-		return "", err
-	}
-
-	fmt.Fprintf(os.Stderr, "Note: Use 'eval $(sniprun %s --source)' to execute in current shell\n", s.Name)
-	return command, nil
-}
\ No newline at end of file
+package snip
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/mini-page/sniprun/internal/sandbox"
+)
+
+// ExecOptions controls where a snip command reads input and writes output
+type ExecOptions struct {
+	Dir    string // working directory, empty for the current one
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Env holds NAME=value pairs added to the environment of the command
+	Env []string
+
+	// Sandbox runs the command with restrictions when set. Restrictions the
+	// kernel does not support are reported on Stderr.
+	Sandbox *sandbox.Config
+}
+
+// Execute runs the snip command in a subprocess
+func (s *Snip) Execute(args []string, dryRun bool) error {
+	command, err := s.InterpolateArgs(args)
+	if err != nil {
+		return err
+	}
+
+	// Secret values are never shown
+	shown, _ := s.InterpolateArgs(s.MaskArgs(args))
+
+	if dryRun {
+		fmt.Printf("Would execute: %s\n", shown)
+		return nil
+	}
+
+	fmt.Printf("Executing: %s\n", shown)
+
+	// Connect to stdio
+	return s.Run(command, ExecOptions{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+}
+
+// Run executes an already interpolated command with the given options.
+// A nil Stdin reads from the null device, which is what non-interactive
+// callers such as the scheduler daemon want.
+func (s *Snip) Run(command string, opts ExecOptions) error {
+	// Determine shell based on OS
+	argv := []string{"sh", "-c", command}
+	if runtime.GOOS == "windows" {
+		argv = []string{"powershell", "-Command", command}
+	}
+
+	var cmd *exec.Cmd
+	if opts.Sandbox != nil {
+		warnings := opts.Stderr
+		if warnings == nil {
+			warnings = os.Stderr
+		}
+
+		var err error
+		cmd, err = sandbox.Command(opts.Sandbox, argv, warnings)
+		if err != nil {
+			return err
+		}
+	} else {
+		cmd = exec.Command(argv[0], argv[1:]...)
+	}
+
+	cmd.Dir = opts.Dir
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	cmd.Stdin = opts.Stdin
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+
+	return cmd.Run()
+}
+
+// ExecuteInShell runs the command and returns output for evaluation in current shell
+// This is for advanced use cases like cd, export, etc.
+func (s *Snip) ExecuteInShell(args []string) (string, error) {
+	command, err := s.InterpolateArgs(args)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(os.Stderr, "Note: Use 'eval $(sniprun %s --source)' to execute in current shell\n", s.Name)
+	return command, nil
+}
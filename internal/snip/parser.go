@@ -8,12 +8,12 @@ import (
 // and returns a map of argument names to their values, or an error.
 func ParseSnipArguments(s *Snip, rawArgs []string) (map[string]string, error) {
 	if len(rawArgs) != len(s.Args) {
-		return nil, fmt.Errorf("expected %d arguments (%v), got %d", len(s.Args), s.Args, len(rawArgs))
+		return nil, fmt.Errorf("expected %d arguments (%v), got %d", len(s.Args), s.ArgNames(), len(rawArgs))
 	}
 
 	parsedArgs := make(map[string]string)
-	for i, argName := range s.Args {
-		parsedArgs[argName] = rawArgs[i]
+	for i, arg := range s.Args {
+		parsedArgs[arg.Name] = rawArgs[i]
 	}
 
 	return parsedArgs, nil
@@ -1,121 +1,249 @@
-package snip
-
-import (
-	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
-
-	"gopkg.in/yaml.v3"
-)
-
-type Snip struct {
-	Name        string   `yaml:"name"`
-	Description string   `yaml:"description"`
-	Command     string   `yaml:"command"`
-	Args        []string `yaml:"args"`
-	Category    string   `yaml:"category"`
-	Trust       string   `yaml:"trust"` // community | local | verified
-}
-
-// LoadSnip reads a snip from a YAML file
-func LoadSnip(path string) (*Snip, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read snip: %w", err)
-	}
-
-	var snip Snip
-	if err := yaml.Unmarshal(data, &snip); err != nil {
-		return nil, fmt.Errorf("failed to parse snip: %w", err)
-	}
-
-	return &snip, nil
-}
-
-// SaveSnip writes a snip to a YAML file
-func SaveSnip(snip *Snip, path string) error {
-	data, err := yaml.Marshal(snip)
-	if err != nil {
-		return fmt.Errorf("failed to marshal snip: %w", err)
-	}
-
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("failed to write snip: %w", err)
-	}
-
-	return nil
-}
-
-// InterpolateArgs replaces {{arg}} placeholders with actual values
-func (s *Snip) InterpolateArgs(args []string) (string, error) {
-	command := s.Command
-
-	// Check if we have the right number of arguments
-	if len(args) != len(s.Args) {
-		return "", fmt.Errorf("expected %d arguments (%v), got %d", len(s.Args), s.Args, len(args))
-	}
-
-	// Replace placeholders
-	for i, argName := range s.Args {
-		placeholder := fmt.Sprintf("{{%s}}", argName)
-		command = strings.ReplaceAll(command, placeholder, args[i])
-	}
-
-	return command, nil
-}
-
-// ListSnips returns all available snips from local and community directories
-func ListSnips(configDir string) (map[string]*Snip, error) {
-	snips := make(map[string]*Snip)
-
-	// Load from both directories
-	dirs := []string{
-		filepath.Join(configDir, "snips", "local"),
-		filepath.Join(configDir, "snips", "community"),
-	}
-
-	for _, dir := range dirs {
-		entries, err := os.ReadDir(dir)
-		if err != nil {
-			continue // Skip if directory doesn't exist
-		}
-
-		for _, entry := range entries {
-			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
-				continue
-			}
-
-			path := filepath.Join(dir, entry.Name())
-			snip, err := LoadSnip(path)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to load %s: %v\n", path, err)
-				continue
-			}
-
-			// Use snip name as key, local overrides community
-			snips[snip.Name] = snip
-		}
-	}
-
-	return snips, nil
-}
-
-// FindSnip locates a snip by name
-func FindSnip(configDir, name string) (*Snip, string, error) {
-	// Check local first
-	localPath := filepath.Join(configDir, "snips", "local", name+".yaml")
-	if _, err := os.Stat(localPath); err == nil {
-		snip, err := LoadSnip(localPath)
-		return snip, localPath, err
-	}
-
-	// Check community
-	communityPath := filepath.Join(configDir, "snips", "community", name+".yaml")
-	if _, err := os.Stat(communityPath); err == nil {
-		snip, err := LoadSnip(communityPath)
-		return snip, communityPath, err
-	}
-
-	return nil, "", fmt.Errorf("snip '%s' not found", name)
+package snip
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mini-page/sniprun/internal/vault"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Snip struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Command     string   `yaml:"command"`
+	Args        []Arg    `yaml:"args"`
+	Env         []EnvVar `yaml:"env,omitempty"`
+	Category    string   `yaml:"category"`
+	Trust       string   `yaml:"trust"` // community | local | verified
+
+	// DeclaredTrust is the trust level the file claimed when it differs
+	// from the one derived from where it was loaded
+	DeclaredTrust string `yaml:"-"`
+
+	// Capabilities is nil when the snip does not declare any
+	Capabilities *Capabilities `yaml:"capabilities,omitempty"`
+
+	// Confirm asks before every run, whatever the security check finds
+	Confirm bool `yaml:"confirm,omitempty"`
+	// Destructive snips are confirmed by typing ConfirmText out
+	Destructive bool `yaml:"destructive,omitempty"`
+	// Prompt replaces the default confirmation question
+	Prompt string `yaml:"prompt,omitempty"`
+	// ConfirmText is what has to be typed to run a destructive snip, the
+	// snip name by default. It may use {{arg}} placeholders, e.g. the
+	// database about to be dropped.
+	ConfirmText string `yaml:"confirm_text,omitempty"`
+
+	// Profile is the name of the profile the snip is run with, and Vars
+	// the profile's variables filling {{name}} placeholders that are not
+	// arguments (see UseProfile)
+	Profile string            `yaml:"-"`
+	Vars    map[string]string `yaml:"-"`
+}
+
+// LoadSnip reads a snip from a YAML file
+func LoadSnip(path string) (*Snip, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snip: %w", err)
+	}
+
+	return ParseSnip(data)
+}
+
+// ParseSnip reads a snip from YAML
+func ParseSnip(data []byte) (*Snip, error) {
+	var snip Snip
+	if err := yaml.Unmarshal(data, &snip); err != nil {
+		return nil, fmt.Errorf("failed to parse snip: %w", err)
+	}
+
+	return &snip, nil
+}
+
+// SaveSnip writes a snip to a YAML file
+func SaveSnip(snip *Snip, path string) error {
+	data, err := yaml.Marshal(snip)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snip: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write snip: %w", err)
+	}
+
+	return nil
+}
+
+// SetTrust replaces the trust level a snip file declares with the one
+// derived from where it was loaded. A file cannot raise its own trust, so
+// a differing declaration is only kept in DeclaredTrust to flag it.
+func (s *Snip) SetTrust(trust string) {
+	if s.Trust != "" && s.Trust != trust {
+		s.DeclaredTrust = s.Trust
+	}
+	s.Trust = trust
+}
+
+// NeedsConfirmation reports whether the snip asks before every run
+func (s *Snip) NeedsConfirmation() bool {
+	return s.Confirm || s.Destructive
+}
+
+// TypedConfirmation returns what has to be typed to run a destructive snip
+// with the given arguments
+func (s *Snip) TypedConfirmation(args []string) string {
+	if s.ConfirmText == "" {
+		return s.Name
+	}
+	text := s.InterpolateVars(s.ConfirmText)
+	for i, arg := range s.Args {
+		if i < len(args) {
+			text = strings.ReplaceAll(text, fmt.Sprintf("{{%s}}", arg.Name), args[i])
+		}
+	}
+	return text
+}
+
+// InterpolateArgs replaces {{arg}} placeholders with actual values.
+// Profile variables are filled in first, so placeholders inside argument
+// values are left as given.
+func (s *Snip) InterpolateArgs(args []string) (string, error) {
+	command := s.InterpolateVars(s.Command)
+
+	// Check if we have the right number of arguments
+	if len(args) != len(s.Args) {
+		return "", fmt.Errorf("expected %d arguments (%v), got %d", len(s.Args), s.ArgNames(), len(args))
+	}
+
+	// Replace placeholders
+	for i, arg := range s.Args {
+		placeholder := fmt.Sprintf("{{%s}}", arg.Name)
+		command = strings.ReplaceAll(command, placeholder, args[i])
+	}
+
+	return command, nil
+}
+
+// ListSnips returns all available snips from the local and community
+// directories and the vault. When names clash the snip FindSnip would
+// return wins: local, then vault, then community.
+func ListSnips(configDir string) (map[string]*Snip, error) {
+	snips := make(map[string]*Snip)
+
+	// Load community first so local snips override them
+	dirs := []string{
+		filepath.Join(configDir, "snips", "community"),
+		filepath.Join(configDir, "snips", "local"),
+	}
+
+	verified := LoadVerified(dirs[0])
+	local := make(map[string]bool)
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue // Skip if directory doesn't exist
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			snip, err := LoadSnip(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to load %s: %v\n", path, err)
+				continue
+			}
+			if dir == dirs[0] {
+				snip.SetTrust(communityTrust(path, verified))
+			} else {
+				snip.SetTrust("local")
+				local[snip.Name] = true
+			}
+
+			snips[snip.Name] = snip
+		}
+	}
+
+	// Snips in the vault are only listed while it is unlocked
+	vaultSnips, err := loadVaultSnips(configDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	for name, snip := range vaultSnips {
+		if !local[name] {
+			snips[name] = snip
+		}
+	}
+
+	return snips, nil
+}
+
+// SortNames returns the names of the given snips ordered by score, highest
+// first, falling back to alphabetical order. A nil score map sorts by name.
+func SortNames(snips map[string]*Snip, scores map[string]float64) []string {
+	names := make([]string, 0, len(snips))
+	for name := range snips {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if scores[names[i]] != scores[names[j]] {
+			return scores[names[i]] > scores[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	return names
+}
+
+// IsCommunity reports whether a snip file was synced from the community
+// repository
+func IsCommunity(configDir, path string) bool {
+	return filepath.Dir(path) == filepath.Join(configDir, "snips", "community")
+}
+
+// FindSnip locates a snip by name
+func FindSnip(configDir, name string) (*Snip, string, error) {
+	// Check local first
+	localPath := filepath.Join(configDir, "snips", "local", name+".yaml")
+	if _, err := os.Stat(localPath); err == nil {
+		snip, err := LoadSnip(localPath)
+		if err == nil {
+			snip.SetTrust("local")
+		}
+		return snip, localPath, err
+	}
+
+	// Check the vault
+	vaultSnips, err := loadVaultSnips(configDir)
+	if err != nil {
+		return nil, "", err
+	}
+	if snip, ok := vaultSnips[name]; ok {
+		return snip, VaultPath(configDir, name), nil
+	}
+
+	// Check community
+	communityPath := filepath.Join(configDir, "snips", "community", name+".yaml")
+	if _, err := os.Stat(communityPath); err == nil {
+		snip, err := LoadSnip(communityPath)
+		if err == nil {
+			snip.SetTrust(communityTrust(communityPath, LoadVerified(filepath.Dir(communityPath))))
+		}
+		return snip, communityPath, err
+	}
+
+	if vault.Exists(configDir) && !VaultUnlocked(configDir) {
+		return nil, "", fmt.Errorf("snip '%s' not found (the vault is locked, run 'sniprun vault unlock' to use its snips)", name)
+	}
+	return nil, "", fmt.Errorf("snip '%s' not found", name)
 }
\ No newline at end of file
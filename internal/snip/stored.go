@@ -0,0 +1,36 @@
+package snip
+
+import "github.com/mini-page/sniprun/internal/secrets"
+
+// StoredSecret is a likely secret written into a snip file
+type StoredSecret struct {
+	// Field is where it was found: "command", "env NAME" or "arg NAME"
+	Field string
+	secrets.Match
+}
+
+// StoredSecrets scans the parts of a snip kept in plain text for likely
+// secrets: the command, environment variable values and argument sources.
+// A value given for a secret environment variable is always reported.
+func (s *Snip) StoredSecrets() []StoredSecret {
+	var found []StoredSecret
+	scan := func(field, text string) {
+		for _, m := range secrets.Scan(text) {
+			found = append(found, StoredSecret{Field: field, Match: m})
+		}
+	}
+
+	scan("command", s.Command)
+	for _, e := range s.Env {
+		field := "env " + e.Name
+		if e.Secret && e.Value != "" {
+			found = append(found, StoredSecret{Field: field, Match: secrets.Match{Kind: "secret value", Value: e.Value, End: len(e.Value), EnvName: e.Name}})
+			continue
+		}
+		scan(field, e.Value)
+	}
+	for _, a := range s.Args {
+		scan("arg "+a.Name, a.From)
+	}
+	return found
+}
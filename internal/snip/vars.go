@@ -0,0 +1,71 @@
+package snip
+
+import (
+	"regexp"
+	"sort"
+)
+
+// placeholderPattern matches {{name}} placeholders, but not templates of
+// other tools such as docker's {{.Names}}
+var placeholderPattern = regexp.MustCompile(`\{\{([A-Za-z_][A-Za-z0-9_-]*)\}\}`)
+
+// UseProfile makes the variables of a profile available to the snip's
+// command, environment and confirmation text. Arguments take precedence
+// over variables of the same name.
+func (s *Snip) UseProfile(name string, vars map[string]string) {
+	s.Profile = name
+	s.Vars = vars
+}
+
+// InterpolateVars replaces {{name}} placeholders of profile variables in a
+// text, leaving those of arguments alone
+func (s *Snip) InterpolateVars(text string) string {
+	if len(s.Vars) == 0 {
+		return text
+	}
+
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		if s.isArg(name) {
+			return placeholder
+		}
+		if value, ok := s.Vars[name]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// UndefinedVars returns the placeholders in the command and environment
+// that are neither arguments nor variables of the profile, sorted by name
+func (s *Snip) UndefinedVars() []string {
+	texts := []string{s.Command}
+	for _, e := range s.Env {
+		texts = append(texts, e.Value)
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, text := range texts {
+		for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			name := m[1]
+			if _, ok := s.Vars[name]; ok || s.isArg(name) || seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func (s *Snip) isArg(name string) bool {
+	for _, arg := range s.Args {
+		if arg.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package snip
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mini-page/sniprun/internal/vault"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrVaultLocked is returned when a vault snip is changed while the vault
+// is locked
+var ErrVaultLocked = errors.New("the vault is locked, run 'sniprun vault unlock'")
+
+// VaultPath is the path FindSnip reports for a snip stored in the vault
+func VaultPath(configDir, name string) string {
+	return vault.Path(configDir) + "#" + name
+}
+
+// IsVault reports whether a path returned by FindSnip is in the vault
+func IsVault(configDir, path string) bool {
+	return strings.HasPrefix(path, vault.Path(configDir)+"#")
+}
+
+// VaultUnlocked reports whether there is an unlocked vault session
+func VaultUnlocked(configDir string) bool {
+	_, _, ok := vault.SessionKey(configDir)
+	return ok
+}
+
+// loadVaultSnips returns the snips stored in the vault, or none while it is
+// locked. Vault snips are the user's own and so trusted as local.
+func loadVaultSnips(configDir string) (map[string]*Snip, error) {
+	key, _, ok := vault.SessionKey(configDir)
+	if !ok {
+		return nil, nil
+	}
+
+	contents, err := vault.Load(configDir, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vault: %w", err)
+	}
+
+	snips := make(map[string]*Snip, len(contents.Snips))
+	for name, data := range contents.Snips {
+		snip, err := ParseSnip([]byte(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to load vault snip %s: %v\n", name, err)
+			continue
+		}
+		snip.SetTrust("local")
+		snips[name] = snip
+	}
+	return snips, nil
+}
+
+// SaveVaultSnip stores a snip in the unlocked vault
+func SaveVaultSnip(configDir string, snip *Snip) error {
+	return updateVault(configDir, func(c *vault.Contents) error {
+		data, err := yaml.Marshal(snip)
+		if err != nil {
+			return fmt.Errorf("failed to marshal snip: %w", err)
+		}
+		c.Snips[snip.Name] = string(data)
+		return nil
+	})
+}
+
+// RemoveVaultSnip deletes a snip from the unlocked vault
+func RemoveVaultSnip(configDir, name string) error {
+	return updateVault(configDir, func(c *vault.Contents) error {
+		if _, ok := c.Snips[name]; !ok {
+			return fmt.Errorf("snip '%s' is not in the vault", name)
+		}
+		delete(c.Snips, name)
+		return nil
+	})
+}
+
+func updateVault(configDir string, change func(*vault.Contents) error) error {
+	key, _, ok := vault.SessionKey(configDir)
+	if !ok {
+		return ErrVaultLocked
+	}
+
+	contents, err := vault.Load(configDir, key)
+	if err != nil {
+		return err
+	}
+	if err := change(contents); err != nil {
+		return err
+	}
+	return vault.Save(configDir, key, contents)
+}
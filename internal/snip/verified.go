@@ -0,0 +1,67 @@
+package snip
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// VerifiedFile records, in the community dir, which snips were covered by a
+// valid manifest signature when they were synced
+const VerifiedFile = "verified.json"
+
+// Verified maps snip file names to their SHA-256 as listed in the signed
+// manifest, along with the name of the key that signed it
+type Verified struct {
+	Key   string            `json:"key"`
+	Files map[string]string `json:"files"`
+}
+
+// LoadVerified reads the verification record of a directory. A missing or
+// unreadable record covers nothing.
+func LoadVerified(dir string) *Verified {
+	data, err := os.ReadFile(filepath.Join(dir, VerifiedFile))
+	if err != nil {
+		return &Verified{}
+	}
+
+	var v Verified
+	if err := json.Unmarshal(data, &v); err != nil {
+		return &Verified{}
+	}
+	return &v
+}
+
+// SaveVerified writes the verification record of a directory
+func SaveVerified(dir string, v *Verified) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, VerifiedFile), data, 0644)
+}
+
+// Covers reports whether a snip file still has the content that was signed
+func (v *Verified) Covers(path string) bool {
+	want, ok := v.Files[filepath.Base(path)]
+	if !ok {
+		return false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) == want
+}
+
+// communityTrust is verified for community snips covered by the signature
+func communityTrust(path string, v *Verified) string {
+	if v.Covers(path) {
+		return "verified"
+	}
+	return "community"
+}
@@ -0,0 +1,154 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// session keeps the vault unlocked between runs until it expires
+type session struct {
+	Key     Key       `json:"key"`
+	Expires time.Time `json:"expires"`
+}
+
+// sessionDir is the per-user runtime directory. Without one, a directory
+// only the user can open is made in the user's cache directory; the shared
+// temporary directory is never used.
+func sessionDir() (string, error) {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir, nil
+	}
+
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("no runtime directory for the vault session: set XDG_RUNTIME_DIR")
+	}
+	dir := filepath.Join(cache, "sniprun", "run")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create vault session directory: %w", err)
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to create vault session directory: %w", err)
+	}
+	if !info.IsDir() || !private(info, 0700) {
+		return "", fmt.Errorf("vault session directory %s must be a directory owned by you with mode 0700", dir)
+	}
+	return dir, nil
+}
+
+// sessionPath keeps the session outside the config directory, so it does
+// not end up in backups or synced dotfiles next to the vault
+func sessionPath(configDir string) (string, error) {
+	dir, err := sessionDir()
+	if err != nil {
+		return "", err
+	}
+
+	abs, err := filepath.Abs(configDir)
+	if err != nil {
+		abs = configDir
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, fmt.Sprintf("sniprun-vault-%d-%s", os.Getuid(), hex.EncodeToString(sum[:8]))), nil
+}
+
+// StartSession keeps the vault unlocked for ttl and returns when it locks
+func StartSession(configDir string, key Key, ttl time.Duration) (time.Time, error) {
+	s := session{Key: key, Expires: time.Now().Add(ttl)}
+	data, err := json.Marshal(&s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to marshal vault session: %w", err)
+	}
+
+	path, err := sessionPath(configDir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	os.Remove(path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to write vault session: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		os.Remove(path)
+		return time.Time{}, fmt.Errorf("failed to write vault session: %w", err)
+	}
+	return s.Expires, nil
+}
+
+// readSession reads the session file. A file that is not a regular file
+// owned by the user with mode 0600 is not trusted and is left alone.
+func readSession(path string) (*session, bool) {
+	info, err := os.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() || !private(info, 0600) {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		os.Remove(path)
+		return nil, false
+	}
+	return &s, true
+}
+
+// SessionKey returns the key of an unlocked vault. An expired session is
+// removed.
+func SessionKey(configDir string) (Key, time.Time, bool) {
+	path, err := sessionPath(configDir)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	s, ok := readSession(path)
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	if time.Now().After(s.Expires) {
+		os.Remove(path)
+		return nil, time.Time{}, false
+	}
+	return s.Key, s.Expires, true
+}
+
+// ExpireSession waits until expires and then removes the session, unless
+// the vault was locked or unlocked again in the meantime
+func ExpireSession(configDir string, expires time.Time) error {
+	time.Sleep(time.Until(expires))
+
+	path, err := sessionPath(configDir)
+	if err != nil {
+		return err
+	}
+	s, ok := readSession(path)
+	if !ok || !s.Expires.Equal(expires) {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to lock vault: %w", err)
+	}
+	return nil
+}
+
+// Lock ends the session, if any
+func Lock(configDir string) error {
+	path, err := sessionPath(configDir)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to lock vault: %w", err)
+	}
+	return nil
+}
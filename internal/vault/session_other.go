@@ -0,0 +1,17 @@
+//go:build !unix
+
+package vault
+
+import (
+	"os"
+	"os/exec"
+)
+
+// private leaves ownership and permissions to the ACLs of the user's
+// profile directory, which Go does not report as mode bits
+func private(info os.FileInfo, perm os.FileMode) bool {
+	return true
+}
+
+// Detach leaves cmd as it is; it already outlives the console
+func Detach(cmd *exec.Cmd) {}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package vault
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// private reports whether a session file or directory belongs to the user
+// and has exactly the given permissions
+func private(info os.FileInfo, perm os.FileMode) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(st.Uid) == os.Getuid() && info.Mode().Perm() == perm
+}
+
+// Detach starts cmd in its own session so it outlives the terminal
+func Detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
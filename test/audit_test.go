@@ -0,0 +1,127 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mini-page/sniprun/internal/audit"
+	"github.com/mini-page/sniprun/internal/security"
+	"github.com/mini-page/sniprun/internal/snip"
+)
+
+func TestAuditGroupsByRisk(t *testing.T) {
+	var items []*audit.Item
+	for _, command := range []string{"ls", "rm -rf /", "broken", "rm -rf build"} {
+		items = append(items, &audit.Item{Snip: &snip.Snip{Name: command}, Path: "/snips/x.yaml", Command: command})
+	}
+
+	var running, peak int32
+	check := func(s *snip.Snip, command string) (*security.ValidationResult, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+
+		if command == "broken" {
+			return nil, errors.New("offline")
+		}
+		return (&security.RulesValidator{}).Validate(&security.Request{Command: command})
+	}
+
+	entries := audit.Run(items, audit.Options{Workers: 2, Check: check})
+	var order []string
+	for _, e := range entries {
+		order = append(order, e.Snip)
+	}
+	if got := strings.Join(order, "|"); got != "rm -rf /|broken|rm -rf build|ls" {
+		t.Errorf("unexpected order %s", got)
+	}
+	if peak > 2 {
+		t.Errorf("expected at most 2 concurrent checks, got %d", peak)
+	}
+	if !audit.Failed(entries, security.RiskDangerous) {
+		t.Error("expected the audit to fail")
+	}
+
+	var buf bytes.Buffer
+	if err := audit.WriteSARIF(&buf, entries, "/snips"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var sarif struct {
+		Runs []struct {
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &sarif); err != nil {
+		t.Fatalf("invalid SARIF: %v", err)
+	}
+	results := sarif.Runs[0].Results
+	if len(results) != 3 || results[0].RuleID != "rm-root" || results[0].Level != "error" {
+		t.Errorf("unexpected SARIF results %+v", results)
+	}
+	if uri := results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "x.yaml" {
+		t.Errorf("expected a path relative to the base dir, got %s", uri)
+	}
+}
+
+func TestAuditStoredSecrets(t *testing.T) {
+	token := "ghp_" + strings.Repeat("a1B2", 9)
+	s := &snip.Snip{
+		Name:    "deploy",
+		Command: "deploy --token " + token,
+		Env:     []snip.EnvVar{{Name: "API_TOKEN", Value: "hunter22", Secret: true}, {Name: "REGION", Value: "eu-west-1"}},
+		Args:    []snip.Arg{{Name: "key", From: "cmd:echo " + token}},
+	}
+
+	var seen string
+	check := func(s *snip.Snip, command string) (*security.ValidationResult, error) {
+		seen = command
+		return (&security.RulesValidator{}).Validate(&security.Request{Command: command})
+	}
+	entries := audit.Run([]*audit.Item{{Snip: s, Path: "/snips/deploy.yaml", Command: s.Command}}, audit.Options{Check: check})
+
+	e := entries[0]
+	if strings.Contains(seen, token) || strings.Contains(e.Command, token) {
+		t.Errorf("expected the token to be masked, got %q and %q", seen, e.Command)
+	}
+	if e.RiskLevel != security.RiskWarning {
+		t.Errorf("expected a warning, got %s", e.RiskLevel)
+	}
+
+	var fields []string
+	for _, f := range e.Findings {
+		if f.RuleID == audit.RuleStoredSecret {
+			fields = append(fields, strings.SplitN(f.Message, " contains", 2)[0])
+		}
+	}
+	if got := strings.Join(fields, "|"); got != "command|env API_TOKEN|arg key" {
+		t.Errorf("unexpected findings %s", got)
+	}
+
+	var buf bytes.Buffer
+	audit.WriteText(&buf, entries)
+	if strings.Contains(buf.String(), token) || strings.Contains(buf.String(), "hunter22") {
+		t.Errorf("expected no secrets in the report:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "[stored-secret] env API_TOKEN") {
+		t.Errorf("expected the findings in the report:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mini-page/sniprun/internal/auditlog"
+)
+
+func writeAuditLog(t *testing.T, n int) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i := 0; i < n; i++ {
+		err := auditlog.Append(dir, &auditlog.Record{Event: auditlog.EventRun, Snip: "deploy", CommandHash: auditlog.HashCommand("make deploy")})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestAuditLogChain(t *testing.T) {
+	dir := writeAuditLog(t, 3)
+
+	count, err := auditlog.Verify(dir)
+	if err != nil || count != 3 {
+		t.Fatalf("expected 3 intact records, got %d, %v", count, err)
+	}
+
+	records, _ := auditlog.Load(dir)
+	if records[0].PrevHash != "" || records[1].PrevHash != records[0].Hash || records[2].Seq != 3 {
+		t.Errorf("records are not chained: %+v", records)
+	}
+}
+
+func TestAuditLogDetectsTampering(t *testing.T) {
+	lines := func(dir string) []string {
+		data, _ := os.ReadFile(auditlog.Path(dir))
+		return strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	tests := map[string]func([]string) []string{
+		"edited":    func(l []string) []string { l[1] = strings.Replace(l[1], "deploy", "deplox", 1); return l },
+		"deleted":   func(l []string) []string { return append(l[:1], l[2:]...) },
+		"truncated": func(l []string) []string { return l[:2] },
+	}
+
+	for name, tamper := range tests {
+		dir := writeAuditLog(t, 3)
+		data := strings.Join(tamper(lines(dir)), "")
+		os.WriteFile(filepath.Join(dir, "audit-log.jsonl"), []byte(data), 0600)
+
+		if _, err := auditlog.Verify(dir); !errors.Is(err, auditlog.ErrTampered) {
+			t.Errorf("%s: expected tampering to be detected, got %v", name, err)
+		}
+	}
+}
+
+func TestAuditLogAppendsAfterCorruptLine(t *testing.T) {
+	dir := writeAuditLog(t, 2)
+
+	// A corrupt line is reported by Verify but does not stop later records
+	f, _ := os.OpenFile(auditlog.Path(dir), os.O_WRONLY|os.O_APPEND, 0600)
+	f.WriteString("not json\n")
+	f.Close()
+
+	r := &auditlog.Record{Event: auditlog.EventRun, Snip: "deploy"}
+	if err := auditlog.Append(dir, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Seq != 3 {
+		t.Errorf("expected the chain to continue at 3, got %d", r.Seq)
+	}
+	if _, err := auditlog.Verify(dir); !errors.Is(err, auditlog.ErrTampered) {
+		t.Errorf("expected the corrupt line to be reported, got %v", err)
+	}
+}
+
+func TestAuditLogBrokenHead(t *testing.T) {
+	dir := writeAuditLog(t, 1)
+	head := filepath.Join(dir, "audit-log.head")
+
+	os.WriteFile(head, []byte("garbage"), 0600)
+	if err := auditlog.Check(dir); err == nil {
+		t.Error("expected a corrupt head to fail the check")
+	}
+	if err := auditlog.Append(dir, &auditlog.Record{Event: auditlog.EventRun}); err == nil {
+		t.Error("expected a corrupt head to fail the append")
+	}
+
+	// Without a head the chain cannot continue, unless the log is empty
+	os.Remove(head)
+	if err := auditlog.Append(dir, &auditlog.Record{Event: auditlog.EventRun}); err == nil {
+		t.Error("expected a missing head to fail the append")
+	}
+	if err := auditlog.Check(t.TempDir()); err != nil {
+		t.Errorf("unexpected error for a new log: %v", err)
+	}
+}
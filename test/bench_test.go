@@ -0,0 +1,81 @@
+package test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/bench"
+)
+
+func TestBenchStats(t *testing.T) {
+	ms := func(values ...float64) []time.Duration {
+		times := make([]time.Duration, len(values))
+		for i, v := range values {
+			times[i] = time.Duration(v * float64(time.Millisecond))
+		}
+		return times
+	}
+
+	tests := []struct {
+		name     string
+		times    []time.Duration
+		mean     time.Duration
+		median   time.Duration
+		stddev   time.Duration
+		outliers int
+	}{
+		{"empty", nil, 0, 0, 0, 0},
+		{"single run", ms(5), 5 * time.Millisecond, 5 * time.Millisecond, 0, 0},
+		{"odd count", ms(3, 1, 2), 2 * time.Millisecond, 2 * time.Millisecond, time.Millisecond, 0},
+		// The median of an even count is the mean of the middle two
+		{"even count", ms(40, 10, 30, 20), 25 * time.Millisecond, 25 * time.Millisecond, 12909944, 0},
+		{"even count with ties", ms(10, 10, 20, 20), 15 * time.Millisecond, 15 * time.Millisecond, 5773503, 0},
+		// Q1 11.25, Q3 13.75, so anything above 17.5 is an outlier
+		{"high outlier", ms(10, 11, 12, 13, 14, 100), 26666666, 12500 * time.Microsecond, 35953673, 1},
+		{"just inside the fence", ms(10, 11, 12, 13, 14, 17.5), 12916666, 12500 * time.Microsecond, 2653613, 0},
+		// Q1 50.5, Q3 53.5, the fences are 46 and 58
+		{"outliers on both sides", ms(1, 50, 51, 52, 53, 54, 200), 65857142, 52 * time.Millisecond, 62143459, 2},
+	}
+
+	for _, tt := range tests {
+		st := bench.Compute(tt.times)
+		if st.Mean != tt.mean || st.Median != tt.median || st.Outliers != tt.outliers {
+			t.Errorf("%s: expected mean %s, median %s and %d outliers, got %+v", tt.name, tt.mean, tt.median, tt.outliers, st)
+		}
+		if d := st.StdDev - tt.stddev; d < -time.Microsecond || d > time.Microsecond {
+			t.Errorf("%s: expected stddev %s, got %s", tt.name, tt.stddev, st.StdDev)
+		}
+		if len(tt.times) > 0 && (st.Min > st.Median || st.Max < st.Median) {
+			t.Errorf("%s: expected min <= median <= max, got %+v", tt.name, st)
+		}
+	}
+}
+
+func TestBenchRatio(t *testing.T) {
+	stats := func(mean, stddev time.Duration) bench.Stats {
+		return bench.Stats{Mean: mean, StdDev: stddev}
+	}
+
+	tests := []struct {
+		name  string
+		a, b  bench.Stats
+		ratio float64
+		err   float64
+	}{
+		// The relative errors 10% and 20% add in quadrature
+		{"propagated error", stats(100, 10), stats(200, 40), 2, 2 * math.Sqrt(0.01+0.04)},
+		{"faster", stats(200, 40), stats(100, 10), 0.5, 0.5 * math.Sqrt(0.01+0.04)},
+		{"no spread", stats(100, 0), stats(150, 0), 1.5, 0},
+		{"one side spread", stats(100, 0), stats(100, 5), 1, 0.05},
+		{"no timings", stats(0, 0), stats(100, 10), 0, 0},
+		{"no timings for b", stats(100, 10), bench.Stats{}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		ratio, err := bench.Ratio(tt.a, tt.b)
+		if math.Abs(ratio-tt.ratio) > 1e-9 || math.Abs(err-tt.err) > 1e-9 {
+			t.Errorf("%s: expected %.4f ± %.4f, got %.4f ± %.4f", tt.name, tt.ratio, tt.err, ratio, err)
+		}
+	}
+}
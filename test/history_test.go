@@ -0,0 +1,113 @@
+package test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/history"
+	"github.com/mini-page/sniprun/internal/snip"
+)
+
+func TestHistoryConcurrentAppends(t *testing.T) {
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := history.Append(dir, &history.Entry{Snip: "build"}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := history.Load(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 20 {
+		t.Fatalf("expected 20 entries, got %d", len(entries))
+	}
+	for i, e := range entries {
+		if e.ID != i+1 {
+			t.Errorf("expected ID %d, got %d", i+1, e.ID)
+		}
+	}
+}
+
+func TestFrecencyDecay(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	// Each snip was run once, the given time ago
+	tests := []struct {
+		age   time.Duration
+		score float64
+	}{
+		{0, 100},
+		{4*time.Hour - time.Minute, 100},
+		{4 * time.Hour, 80},
+		{23 * time.Hour, 80},
+		{3 * 24 * time.Hour, 60},
+		{10 * 24 * time.Hour, 40},
+		{60 * 24 * time.Hour, 20},
+		{365 * 24 * time.Hour, 10},
+	}
+
+	for _, tt := range tests {
+		scores := history.Frecency([]*history.Entry{{Snip: "build", Timestamp: now.Add(-tt.age)}}, now)
+		if scores["build"] != tt.score {
+			t.Errorf("run %s ago: expected %v, got %v", tt.age, tt.score, scores["build"])
+		}
+	}
+
+	// The same run scores less as time passes
+	entries := []*history.Entry{{Snip: "build", Timestamp: now}}
+	previous := history.Frecency(entries, now)["build"]
+	for _, later := range []time.Duration{time.Hour, 5 * time.Hour, 48 * time.Hour, 20 * 24 * time.Hour, 80 * 24 * time.Hour, 400 * 24 * time.Hour} {
+		score := history.Frecency(entries, now.Add(later))["build"]
+		if score > previous {
+			t.Errorf("expected the score to decay, got %v after %v", score, previous)
+		}
+		previous = score
+	}
+	if previous != 10 {
+		t.Errorf("expected an old run to keep the minimum score, got %v", previous)
+	}
+}
+
+func TestFrecencyOrder(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	runs := func(name string, n int, age time.Duration) []*history.Entry {
+		var entries []*history.Entry
+		for i := 0; i < n; i++ {
+			entries = append(entries, &history.Entry{Snip: name, Timestamp: now.Add(-age), Duration: time.Second, ExitCode: i % 2})
+		}
+		return entries
+	}
+
+	var entries []*history.Entry
+	entries = append(entries, runs("old-favourite", 9, 200*24*time.Hour)...) // 90
+	entries = append(entries, runs("today", 2, time.Hour)...)                // 200
+	entries = append(entries, runs("this-week", 2, 2*24*time.Hour)...)       // 120
+	entries = append(entries, runs("yesterday", 1, 20*time.Hour)...)         // 80
+
+	snips := map[string]*snip.Snip{}
+	for _, name := range []string{"old-favourite", "today", "this-week", "yesterday", "never-run", "also-never"} {
+		snips[name] = &snip.Snip{Name: name}
+	}
+
+	order := snip.SortNames(snips, history.Frecency(entries, now))
+	expected := "today|this-week|old-favourite|yesterday|also-never|never-run"
+	if got := strings.Join(order, "|"); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	st := history.Summarize(entries, now)["old-favourite"]
+	if st.Runs != 9 || st.Failures != 4 || st.AverageDuration() != time.Second || !st.LastRun.Equal(now.Add(-200*24*time.Hour)) {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
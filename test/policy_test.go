@@ -0,0 +1,186 @@
+package test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mini-page/sniprun/internal/policy"
+	"github.com/mini-page/sniprun/internal/snip"
+)
+
+func loadTestPolicy(t *testing.T, system, user string) *policy.Policy {
+	t.Helper()
+	dir := t.TempDir()
+
+	policy.SystemPath = filepath.Join(dir, "system.yaml")
+	t.Cleanup(func() { policy.SystemPath = "/etc/sniprun/policy.yaml" })
+
+	if system != "" {
+		if err := os.WriteFile(policy.SystemPath, []byte(system), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(policy.UserPath(dir), []byte(user), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := policy.Load(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return p
+}
+
+func TestPolicyFirstMatchingRuleDecides(t *testing.T) {
+	p := loadTestPolicy(t, `
+settings:
+  forbid_skip_check: true
+rules:
+  - id: no-prod
+    action: deny
+    pattern: 'psql .*prod'
+`, `
+rules:
+  - id: allow-db
+    action: allow
+    category: db
+  - id: kubectl
+    action: warn
+    command: kubectl
+`)
+
+	tests := []struct {
+		target policy.Target
+		rule   string
+		action policy.Action
+	}{
+		{policy.Target{Command: "psql -h prod", Category: "db"}, "no-prod", policy.Deny},
+		{policy.Target{Command: "psql -h staging", Category: "db"}, "allow-db", policy.Allow},
+		{policy.Target{Command: "echo x && sudo kubectl get pods"}, "kubectl", policy.Warn},
+		{policy.Target{Command: "echo kubectl"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		decision := p.Evaluate(&tt.target)
+		if decision.Action != tt.action {
+			t.Errorf("%q: expected %q, got %q", tt.target.Command, tt.action, decision.Action)
+		}
+		if decision.Rule != nil && decision.Rule.ID != tt.rule {
+			t.Errorf("%q: expected rule %s, got %s", tt.target.Command, tt.rule, decision.Rule.ID)
+		}
+	}
+
+	if err := p.CheckSkip("local"); err == nil {
+		t.Error("expected --skip-check to be forbidden by the system policy")
+	}
+}
+
+func TestPolicyCommunityRequiresValidation(t *testing.T) {
+	p := loadTestPolicy(t, "", `
+settings:
+  community_requires_validation: true
+`)
+
+	if !p.RequiresValidation("community") || p.RequiresValidation("local") {
+		t.Error("expected only community snips to require validation")
+	}
+	if p.CheckSkip("community") == nil || p.CheckSkip("local") != nil {
+		t.Error("expected --skip-check to be refused only for community snips")
+	}
+}
+
+func TestPolicyConfirmationPerTrust(t *testing.T) {
+	p := loadTestPolicy(t, `
+settings:
+  trust:
+    community:
+      confirm: always
+    verified:
+      confirm: never
+`, `
+settings:
+  trust:
+    community:
+      confirm: never
+    local:
+      confirm: always
+`)
+
+	tests := map[string]policy.Confirmation{
+		"community": policy.ConfirmAlways, // the stricter setting wins
+		"verified":  policy.ConfirmNever,
+		"local":     policy.ConfirmAlways,
+		"other":     policy.ConfirmWarning,
+	}
+	for trust, want := range tests {
+		if got := p.Confirmation(trust); got != want {
+			t.Errorf("%s: expected %s, got %s", trust, want, got)
+		}
+	}
+}
+
+func TestPolicyAllowYes(t *testing.T) {
+	if err := loadTestPolicy(t, "", "").CheckYes("local"); err == nil {
+		t.Error("expected --yes to be refused without a policy allowing it")
+	}
+
+	p := loadTestPolicy(t, `
+settings:
+  allow_yes: [local, verified]
+`, `
+settings:
+  allow_yes: [local, community]
+`)
+
+	// Only trust levels both files allow are allowed
+	for trust, allowed := range map[string]bool{"local": true, "verified": false, "community": false} {
+		if err := p.CheckYes(trust); (err == nil) != allowed {
+			t.Errorf("%s: expected allowed=%t, got %v", trust, allowed, err)
+		}
+	}
+}
+
+func TestTypedConfirmation(t *testing.T) {
+	s := &snip.Snip{Name: "drop-db", Args: []snip.Arg{{Name: "db"}}, Destructive: true}
+	if got := s.TypedConfirmation([]string{"orders"}); got != "drop-db" {
+		t.Errorf("expected the snip name by default, got %q", got)
+	}
+
+	s.ConfirmText = "{{db}}"
+	if got := s.TypedConfirmation([]string{"orders"}); got != "orders" {
+		t.Errorf("expected the target name, got %q", got)
+	}
+	if !s.NeedsConfirmation() {
+		t.Error("expected destructive snips to need confirmation")
+	}
+}
+
+func TestInvalidConfigIsFatal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses touch")
+	}
+
+	bin := filepath.Join(t.TempDir(), "sniprun")
+	if out, err := exec.Command("go", "build", "-o", bin, "..").CombinedOutput(); err != nil {
+		t.Fatalf("build failed: %v\n%s", err, out)
+	}
+
+	// A typo must not fall back to the defaults and run with other settings
+	dir := t.TempDir()
+	ran := filepath.Join(dir, "ran")
+	os.MkdirAll(filepath.Join(dir, "snips", "local"), 0755)
+	os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("validator: [rules\n"), 0600)
+	os.WriteFile(filepath.Join(dir, "snips", "local", "hello.yaml"), []byte("name: hello\ncommand: touch "+ran+"\n"), 0644)
+
+	out, err := exec.Command(bin, "--config", dir, "--yes", "run", "hello").CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "failed to parse config") {
+		t.Errorf("expected the config error to be fatal, got %q (%v)", out, err)
+	}
+	if _, err := os.Stat(ran); err == nil {
+		t.Error("expected the snip not to run")
+	}
+}
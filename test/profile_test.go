@@ -0,0 +1,50 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mini-page/sniprun/internal/snip"
+)
+
+func TestProfileVars(t *testing.T) {
+	s := &snip.Snip{
+		Name:        "db-migrate",
+		Command:     `psql {{db_url}} -c "migrate {{version}}" && docker ps --format '{{.Names}}'`,
+		Args:        []snip.Arg{{Name: "version"}},
+		ConfirmText: "{{db_name}}",
+	}
+
+	if got := s.UndefinedVars(); !reflect.DeepEqual(got, []string{"db_url"}) {
+		t.Errorf("expected db_url to be undefined, got %v", got)
+	}
+
+	// Arguments win over variables of the same name
+	s.UseProfile("prod", map[string]string{"db_url": "postgres://db.prod/app", "db_name": "app", "version": "ignored"})
+	if got := s.UndefinedVars(); len(got) != 0 {
+		t.Errorf("expected no undefined variables, got %v", got)
+	}
+
+	command, err := s.InterpolateArgs([]string{"42"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `psql postgres://db.prod/app -c "migrate 42" && docker ps --format '{{.Names}}'`
+	if command != expected {
+		t.Errorf("expected %q, got %q", expected, command)
+	}
+
+	if got := s.TypedConfirmation([]string{"42"}); got != "app" {
+		t.Errorf("expected the confirmation text to use the variable, got %q", got)
+	}
+
+	// Variables are not expanded inside argument values
+	command, err = s.InterpolateArgs([]string{"{{db_url}}"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = `psql postgres://db.prod/app -c "migrate {{db_url}}" && docker ps --format '{{.Names}}'`
+	if command != expected {
+		t.Errorf("expected %q, got %q", expected, command)
+	}
+}
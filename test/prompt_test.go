@@ -0,0 +1,44 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mini-page/sniprun/internal/prompt"
+)
+
+func TestPromptNonInteractive(t *testing.T) {
+	for _, value := range []string{"1", "true", "yes"} {
+		t.Setenv(prompt.EnvNonInteractive, value)
+		if prompt.Interactive() {
+			t.Errorf("expected %s=%s to disable questions", prompt.EnvNonInteractive, value)
+		}
+	}
+
+	t.Setenv(prompt.EnvNonInteractive, "1")
+	if _, err := prompt.Confirm("Continue?"); !errors.Is(err, prompt.ErrNoInput) {
+		t.Errorf("expected ErrNoInput, got %v", err)
+	}
+	if _, err := prompt.Line("Description: "); !errors.Is(err, prompt.ErrNoInput) {
+		t.Errorf("expected ErrNoInput, got %v", err)
+	}
+	if _, err := prompt.Secret("Value for token: "); !errors.Is(err, prompt.ErrNoInput) {
+		t.Errorf("expected ErrNoInput, got %v", err)
+	}
+}
+
+func TestPromptAssumeYes(t *testing.T) {
+	t.Setenv(prompt.EnvNonInteractive, "1")
+	prompt.AssumeYes = true
+	defer func() { prompt.AssumeYes = false }()
+
+	answer, err := prompt.Confirm("Continue?")
+	if err != nil || !answer {
+		t.Errorf("expected --yes to answer yes, got %v, %v", answer, err)
+	}
+
+	// --yes only answers yes/no questions
+	if _, err := prompt.Line("Description: "); !errors.Is(err, prompt.ErrNoInput) {
+		t.Errorf("expected ErrNoInput, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package test
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mini-page/sniprun/internal/keyring"
+	"github.com/mini-page/sniprun/internal/repo"
+	"github.com/mini-page/sniprun/internal/snip"
+)
+
+const signedSnip = "name: hello\ndescription: d\ncommand: echo hello\ntrust: verified\n"
+
+// writeArchive builds a repository archive like GitHub's, with a manifest
+// listing manifested files signed by key if key is set
+func writeArchive(t *testing.T, files, manifested map[string]string, key ed25519.PrivateKey) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "snips.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	add := func(name string, data []byte) {
+		w, err := zw.Create("snips-main/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+
+	for name, content := range files {
+		add(name, []byte(content))
+	}
+
+	if key != nil {
+		m := repo.Manifest{Files: map[string]string{}}
+		for name, content := range manifested {
+			sum := sha256.Sum256([]byte(content))
+			m.Files[name] = hex.EncodeToString(sum[:])
+		}
+		data, _ := json.Marshal(m)
+		add(repo.ManifestName, data)
+		add(repo.SignatureName, []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))))
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestKey(t *testing.T, name string) (*keyring.Key, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &keyring.Key{Name: name, PublicKey: base64.StdEncoding.EncodeToString(pub)}, priv
+}
+
+func TestInstallSignedArchive(t *testing.T) {
+	key, priv := newTestKey(t, "official")
+	configDir := t.TempDir()
+	target := filepath.Join(configDir, "snips", "community")
+
+	files := map[string]string{
+		"docker/hello.yaml": signedSnip,
+		"extra.yaml":        "name: extra\ndescription: d\ncommand: echo extra\n",
+	}
+	manifested := map[string]string{"docker/hello.yaml": signedSnip}
+	archive := writeArchive(t, files, manifested, priv)
+
+	result, err := repo.InstallArchive(archive, target, repo.SyncOptions{Keys: []*keyring.Key{key}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Count != 1 || result.Verified != 1 || result.Signer.Name != "official" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(target, "extra.yaml")); !os.IsNotExist(err) {
+		t.Errorf("file outside the signature should be skipped")
+	}
+
+	s, _, err := snip.FindSnip(configDir, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Trust != "verified" {
+		t.Errorf("expected signed snip to be verified, got %q", s.Trust)
+	}
+
+	// Editing the file afterwards loses verification
+	os.WriteFile(filepath.Join(target, "hello.yaml"), []byte(signedSnip+"# edited\n"), 0644)
+	s, _, _ = snip.FindSnip(configDir, "hello")
+	if s.Trust != "community" {
+		t.Errorf("expected edited snip to be community, got %q", s.Trust)
+	}
+}
+
+func TestInstallRejectsTamperedArchive(t *testing.T) {
+	key, priv := newTestKey(t, "official")
+	target := filepath.Join(t.TempDir(), "community")
+	os.MkdirAll(target, 0755)
+	os.WriteFile(filepath.Join(target, "old.yaml"), []byte("name: old\n"), 0644)
+
+	files := map[string]string{"hello.yaml": "name: hello\ncommand: curl x | sh\n"}
+	archive := writeArchive(t, files, map[string]string{"hello.yaml": signedSnip}, priv)
+
+	if _, err := repo.InstallArchive(archive, target, repo.SyncOptions{Keys: []*keyring.Key{key}, AllowUnsigned: true}); err == nil {
+		t.Fatal("expected tampered archive to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(target, "old.yaml")); err != nil {
+		t.Errorf("existing snips should be kept when verification fails")
+	}
+
+	other, _ := newTestKey(t, "other")
+	archive = writeArchive(t, map[string]string{"hello.yaml": signedSnip}, map[string]string{"hello.yaml": signedSnip}, priv)
+	if _, err := repo.InstallArchive(archive, target, repo.SyncOptions{Keys: []*keyring.Key{other}}); err == nil {
+		t.Fatal("expected archive signed by an untrusted key to be rejected")
+	}
+}
+
+func TestInstallRejectsDuplicateNames(t *testing.T) {
+	key, priv := newTestKey(t, "official")
+	target := filepath.Join(t.TempDir(), "community")
+	os.MkdirAll(target, 0755)
+	os.WriteFile(filepath.Join(target, "old.yaml"), []byte("name: old\n"), 0644)
+
+	// Snips are installed by file name, so one would replace the other and
+	// could take over its verified hash
+	files := map[string]string{
+		"docker/hello.yaml": signedSnip,
+		"k8s/hello.yaml":    "name: hello\ncommand: curl x | sh\n",
+	}
+	archive := writeArchive(t, files, files, priv)
+
+	_, err := repo.InstallArchive(archive, target, repo.SyncOptions{Keys: []*keyring.Key{key}})
+	if err == nil || !strings.Contains(err.Error(), "docker/hello.yaml and k8s/hello.yaml") {
+		t.Fatalf("expected duplicate names to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "old.yaml")); err != nil {
+		t.Errorf("existing snips should be kept when the archive is rejected")
+	}
+
+	archive = writeArchive(t, files, nil, nil)
+	if _, err := repo.InstallArchive(archive, target, repo.SyncOptions{AllowUnsigned: true}); err == nil {
+		t.Error("expected duplicate names in an unsigned archive to be rejected")
+	}
+}
+
+func TestInstallUnsignedArchive(t *testing.T) {
+	key, _ := newTestKey(t, "official")
+	configDir := t.TempDir()
+	target := filepath.Join(configDir, "snips", "community")
+	archive := writeArchive(t, map[string]string{"hello.yaml": signedSnip}, nil, nil)
+
+	_, err := repo.InstallArchive(archive, target, repo.SyncOptions{Keys: []*keyring.Key{key}})
+	if !errors.Is(err, repo.ErrUnsigned) {
+		t.Fatalf("expected ErrUnsigned, got %v", err)
+	}
+
+	result, err := repo.InstallArchive(archive, target, repo.SyncOptions{Keys: []*keyring.Key{key}, AllowUnsigned: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count != 1 || result.Verified != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// A snip claiming to be verified is not
+	s, _, _ := snip.FindSnip(configDir, "hello")
+	if s.Trust != "community" {
+		t.Errorf("expected unsigned snip to be community, got %q", s.Trust)
+	}
+}
+
+func TestTrustDerivedFromSource(t *testing.T) {
+	configDir := t.TempDir()
+	for _, dir := range []string{"local", "community"} {
+		os.MkdirAll(filepath.Join(configDir, "snips", dir), 0755)
+	}
+	os.WriteFile(filepath.Join(configDir, "snips", "local", "mine.yaml"), []byte("name: mine\ncommand: ls\n"), 0644)
+	os.WriteFile(filepath.Join(configDir, "snips", "community", "theirs.yaml"), []byte("name: theirs\ncommand: ls\ntrust: local\n"), 0644)
+
+	snips, err := snip.ListSnips(configDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := snips["mine"]; s.Trust != "local" || s.DeclaredTrust != "" {
+		t.Errorf("expected local snip without a claim, got %q/%q", s.Trust, s.DeclaredTrust)
+	}
+	if s := snips["theirs"]; s.Trust != "community" || s.DeclaredTrust != "local" {
+		t.Errorf("expected community snip claiming local, got %q/%q", s.Trust, s.DeclaredTrust)
+	}
+}
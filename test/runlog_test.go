@@ -0,0 +1,170 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/runlog"
+)
+
+func TestRunLogSizeCap(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := runlog.Create(dir, "build/all", 300)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Hide([]string{"hunter22"})
+	l.Header("command: make all")
+
+	out := l.Stream("out")
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(out, "line %d hunter22\n", i)
+	}
+	fmt.Fprint(out, "unterminated")
+	if err := l.Close(2, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if filepath.Dir(l.Path) != runlog.Dir(dir) || !strings.HasSuffix(l.Path, "-build_all.log") {
+		t.Errorf("unexpected log path %s", l.Path)
+	}
+	data, err := os.ReadFile(l.Path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	// Output stops at the cap, the header and footer are kept
+	if !strings.HasSuffix(lines[0], "# | command: make all") {
+		t.Errorf("expected the header first, got %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[len(lines)-2], "# | output truncated at 300 bytes") {
+		t.Errorf("expected a truncation note, got %q", lines[len(lines)-2])
+	}
+	if !strings.HasSuffix(lines[len(lines)-1], "# | exit 2 after 1s") {
+		t.Errorf("expected the footer last, got %q", lines[len(lines)-1])
+	}
+	if len(lines) < 4 || len(lines) > 8 {
+		t.Errorf("expected a few output lines before the cap, got %d lines", len(lines))
+	}
+	if strings.Contains(string(data), "unterminated") || strings.Contains(string(data), "line 49") {
+		t.Error("expected output past the cap to be dropped")
+	}
+	if strings.Contains(string(data), "hunter22") {
+		t.Error("expected hidden values to be redacted")
+	}
+
+	// Without a cap everything is kept
+	l, err = runlog.Create(dir, "test", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out = l.Stream("err")
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(out, "line %d\n", i)
+	}
+	l.Close(0, time.Second)
+	data, _ = os.ReadFile(l.Path)
+	if !strings.Contains(string(data), "err | line 49") || strings.Contains(string(data), "truncated") {
+		t.Errorf("expected the whole output, got:\n%s", data)
+	}
+}
+
+func TestRunLogPrune(t *testing.T) {
+	dir := t.TempDir()
+	logs := runlog.Dir(dir)
+	if err := os.MkdirAll(logs, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	// Names start with the creation time, oldest first
+	names := []string{
+		"20260101-090000.000-deploy.log",
+		"20260101-100000.000-build.log",
+		"20260102-090000.000-deploy.log",
+		"20260103-090000.000-build.log",
+		"20260104-090000.000-test.log",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(logs, name), []byte("x\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Other files are left alone
+	os.WriteFile(filepath.Join(logs, "notes.txt"), nil, 0600)
+
+	if err := runlog.Prune(dir, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries, _ := os.ReadDir(logs); len(entries) != 6 {
+		t.Errorf("expected keep 0 to delete nothing, got %d files", len(entries))
+	}
+
+	if err := runlog.Prune(dir, 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var kept []string
+	entries, _ := os.ReadDir(logs)
+	for _, e := range entries {
+		kept = append(kept, e.Name())
+	}
+	expected := strings.Join(append(names[2:], "notes.txt"), "|")
+	if got := strings.Join(kept, "|"); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	if latest, _ := runlog.Latest(dir, "deploy"); latest != names[2] {
+		t.Errorf("expected the newest deploy log, got %q", latest)
+	}
+	if latest, _ := runlog.Latest(dir, "lint"); latest != "" {
+		t.Errorf("expected no log, got %q", latest)
+	}
+}
+
+func TestRunLogAppendRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.log")
+
+	for i := 0; i < 3; i++ {
+		f, err := runlog.OpenAppend(path, 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		fmt.Fprintf(f, "run %d....\n", i)
+		f.Close()
+	}
+
+	// Each run fills the log, so only the last two are kept
+	if data, _ := os.ReadFile(path); string(data) != "run 2....\n" {
+		t.Errorf("unexpected log %q", data)
+	}
+	if data, _ := os.ReadFile(path + ".1"); string(data) != "run 1....\n" {
+		t.Errorf("unexpected rotated log %q", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v %v", info.Mode(), err)
+	}
+}
+
+func TestRunLogLimit(t *testing.T) {
+	var out strings.Builder
+	w := runlog.Limit(&out, 8)
+	for _, s := range []string{"12345", "67890", "more"} {
+		if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+			t.Fatalf("unexpected write %d %v", n, err)
+		}
+	}
+	if got := out.String(); got != "12345678\n... output truncated at 8 bytes\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+
+	out.Reset()
+	runlog.Limit(&out, 0).Write([]byte("all of it"))
+	if out.String() != "all of it" {
+		t.Errorf("expected no limit, got %q", out.String())
+	}
+}
@@ -0,0 +1,202 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mini-page/sniprun/internal/schedule"
+)
+
+func TestScheduleConcurrentUpdates(t *testing.T) {
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := schedule.UpdateJobs(dir, func(jobs []*schedule.Job) ([]*schedule.Job, error) {
+				return append(jobs, &schedule.Job{ID: fmt.Sprint(i), Snip: "backup", Cron: "0 * * * *"}), nil
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	jobs, err := schedule.LoadJobs(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(jobs) != 10 {
+		t.Errorf("expected every job to be kept, got %d", len(jobs))
+	}
+
+	// A failed update leaves the jobs as they were
+	err = schedule.UpdateJobs(dir, func(jobs []*schedule.Job) ([]*schedule.Job, error) {
+		_, err := schedule.FindJob(jobs, "missing")
+		return nil, err
+	})
+	if err == nil {
+		t.Error("expected an error")
+	}
+	if jobs, _ := schedule.LoadJobs(dir); len(jobs) != 10 {
+		t.Errorf("expected the jobs to be unchanged, got %d", len(jobs))
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	// 2024-03-04 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		expr  string
+		t     time.Time
+		match bool
+	}{
+		{"* * * * *", at(4, 13, 37), true},
+		{"30 9 * * *", at(4, 9, 30), true},
+		{"30 9 * * *", at(4, 9, 31), false},
+
+		// Ranges, steps and lists
+		{"0 9-17 * * *", at(4, 17, 0), true},
+		{"0 9-17 * * *", at(4, 18, 0), false},
+		{"*/15 * * * *", at(4, 1, 45), true},
+		{"*/15 * * * *", at(4, 1, 50), false},
+		{"5/20 * * * *", at(4, 1, 45), true},
+		{"5/20 * * * *", at(4, 1, 40), false},
+		{"0-30/10 * * * *", at(4, 1, 30), true},
+		{"0-30/10 * * * *", at(4, 1, 40), false},
+		{"0,15,45 * * * *", at(4, 1, 15), true},
+		{"0,15,45 * * * *", at(4, 1, 30), false},
+		{"0 0 * mar,jun *", at(4, 0, 0), true},
+
+		// Sunday is 0 or 7
+		{"0 0 * * 0", at(3, 0, 0), true},
+		{"0 0 * * 7", at(3, 0, 0), true},
+		{"0 0 * * sun", at(3, 0, 0), true},
+		{"0 0 * * 7", at(4, 0, 0), false},
+		{"0 0 * * 1-5", at(4, 0, 0), true},
+		{"0 0 * * 1-5", at(9, 0, 0), false},
+
+		// With both day fields restricted either one matches
+		{"0 0 15 * 1", at(4, 0, 0), true},
+		{"0 0 15 * 1", at(15, 0, 0), true},
+		{"0 0 15 * 1", at(16, 0, 0), false},
+		// With one restricted only that one counts
+		{"0 0 15 * *", at(4, 0, 0), false},
+		{"0 0 * * 1", at(15, 0, 0), false},
+		// A stepped "*" is no restriction
+		{"0 0 */2 * 1", at(4, 0, 0), false},
+		{"0 0 */2 * 1", at(11, 0, 0), true},
+		{"0 0 1 * */2", at(5, 0, 0), false},
+	}
+
+	for _, tt := range tests {
+		c, err := schedule.ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if got := c.Matches(tt.t); got != tt.match {
+			t.Errorf("%q at %s: expected %v, got %v", tt.expr, tt.t.Format(time.RFC1123), tt.match, got)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2025, time.December, 31, 23, 58, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2025, time.December, 31, 23, 59, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 12 * feb *", time.Date(2026, time.February, 1, 12, 30, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, time.January, 1, 9, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2026, time.January, 2, 0, 0, 0, 0, time.UTC)},
+
+		// Impossible dates give up rather than loop
+		{"0 0 30 2 *", time.Time{}},
+		{"0 0 31 4,6,9,11 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		c, err := schedule.ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if got := c.Next(from); !got.Equal(tt.next) {
+			t.Errorf("%q: expected %s, got %s", tt.expr, tt.next, got)
+		}
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"10-5 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+	} {
+		if _, err := schedule.ParseCron(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
+
+func TestScheduleLogs(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "sniprun")
+	if out, err := exec.Command("go", "build", "-o", bin, "..").CombinedOutput(); err != nil {
+		t.Fatalf("build failed: %v\n%s", err, out)
+	}
+
+	dir := t.TempDir()
+	if err := schedule.SaveJobs(dir, []*schedule.Job{{ID: "job1", Snip: "backup", Cron: "0 * * * *"}}); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(schedule.LogDir(dir), 0755)
+	os.WriteFile(schedule.LogPath(dir, "job1"), []byte("one\ntwo\nthree"), 0600)
+	os.WriteFile(filepath.Join(dir, "secret.log"), []byte("not a job log\n"), 0600)
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"job1"}, "one\ntwo\nthree"},
+		{[]string{"job1", "-n", "1"}, "three"},
+		{[]string{"job1", "-n", "2"}, "two\nthree"},
+		{[]string{"job1", "-n", "5"}, "one\ntwo\nthree"},
+	}
+	for _, tt := range tests {
+		out, err := exec.Command(bin, append([]string{"--config", dir, "schedule", "logs"}, tt.args...)...).CombinedOutput()
+		if err != nil || string(out) != tt.expected {
+			t.Errorf("%v: expected %q, got %q (%v)", tt.args, tt.expected, out, err)
+		}
+	}
+
+	// Only IDs of scheduled jobs are read
+	out, err := exec.Command(bin, "--config", dir, "schedule", "logs", "../../secret").CombinedOutput()
+	if err == nil || strings.Contains(string(out), "not a job log") {
+		t.Errorf("expected an unknown ID to be refused, got %q (%v)", out, err)
+	}
+}
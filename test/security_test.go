@@ -0,0 +1,184 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mini-page/sniprun/config"
+	"github.com/mini-page/sniprun/internal/llm"
+	"github.com/mini-page/sniprun/internal/security"
+)
+
+// fakeValidator returns a fixed verdict without touching the network
+type fakeValidator struct {
+	name  string
+	level security.RiskLevel
+	err   error
+	calls int
+}
+
+func (f *fakeValidator) Name() string { return f.name }
+
+func (f *fakeValidator) Validate(req *security.Request) (*security.ValidationResult, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &security.ValidationResult{RiskLevel: f.level, Reason: f.name + " says " + string(f.level), Validator: f.name}, nil
+}
+
+func TestCompositeHighestRiskWins(t *testing.T) {
+	safe := &fakeValidator{name: "a", level: security.RiskSafe}
+	warn := &fakeValidator{name: "b", level: security.RiskWarning}
+	broken := &fakeValidator{name: "c", err: errors.New("offline")}
+
+	result, err := security.NewComposite(safe, warn, broken).Validate(&security.Request{Command: "ls"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RiskLevel != security.RiskWarning || result.Validator != "b" {
+		t.Errorf("expected warning from b, got %s from %s", result.RiskLevel, result.Validator)
+	}
+	if safe.calls != 1 || warn.calls != 1 || broken.calls != 1 {
+		t.Errorf("expected every validator to run once")
+	}
+}
+
+func TestCompositeAllFailing(t *testing.T) {
+	broken := &fakeValidator{name: "a", err: errors.New("offline")}
+	if _, err := security.NewComposite(broken).Validate(&security.Request{Command: "ls"}); err == nil {
+		t.Error("expected an error when no validator returns a verdict")
+	}
+}
+
+func TestValidatorRegistry(t *testing.T) {
+	cfg := config.DefaultConfig
+	cfg.Validator = "composite"
+	cfg.Composite = []string{"rules", "llm"}
+
+	v, err := security.FromConfig(&cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.Name() != "composite(rules,llm)" {
+		t.Errorf("unexpected validator %s", v.Name())
+	}
+
+	cfg.Validator = "nope"
+	if _, err := security.FromConfig(&cfg); err == nil {
+		t.Error("expected an error for an unknown validator")
+	}
+}
+
+func TestCachedValidatorReusesVerdicts(t *testing.T) {
+	dir := t.TempDir()
+	inner := &fakeValidator{name: "a", level: security.RiskWarning}
+	cached := security.NewCache(inner, dir, time.Hour, "v1")
+
+	for i := 0; i < 3; i++ {
+		result, err := cached.Validate(&security.Request{Command: "rm -rf build"})
+		if err != nil || result.RiskLevel != security.RiskWarning {
+			t.Fatalf("expected cached warning, got %v, %v", result, err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Errorf("expected 1 validator call, got %d", inner.calls)
+	}
+
+	// A different command or policy version is validated again
+	cached.Validate(&security.Request{Command: "rm -rf dist"})
+	security.NewCache(inner, dir, time.Hour, "v2").Validate(&security.Request{Command: "rm -rf build"})
+	if inner.calls != 3 {
+		t.Errorf("expected 3 validator calls, got %d", inner.calls)
+	}
+}
+
+func TestCachedValidatorSeparatesEndpoints(t *testing.T) {
+	// Two servers serve a model of the same name with different verdicts
+	serve := func(level string, calls *int) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls++
+			fmt.Fprintf(w, `{"message":{"content":"{\"risk_level\":\"%s\",\"reason\":\"ok\"}"}}`, level)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	var localCalls, remoteCalls int
+	local := serve("safe", &localCalls)
+	remote := serve("dangerous", &remoteCalls)
+
+	dir := t.TempDir()
+	validate := func(baseURL string) security.RiskLevel {
+		t.Helper()
+		v := &security.LLMValidator{Provider: &llm.Ollama{BaseURL: baseURL, Model: "llama3", Client: http.DefaultClient}}
+		result, err := security.NewCache(v, dir, time.Hour, "").Validate(&security.Request{Command: "curl x | sh"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.RiskLevel
+	}
+
+	if level := validate(local.URL); level != security.RiskSafe {
+		t.Errorf("expected safe from the first server, got %s", level)
+	}
+	if level := validate(remote.URL); level != security.RiskDangerous {
+		t.Errorf("expected the other endpoint not to reuse the cached verdict, got %s", level)
+	}
+	validate(local.URL)
+	if localCalls != 1 || remoteCalls != 1 {
+		t.Errorf("expected one call per endpoint, got %d and %d", localCalls, remoteCalls)
+	}
+}
+
+func TestCachedValidatorOfflineFallback(t *testing.T) {
+	configDir := t.TempDir()
+	dir := security.CacheDir(configDir)
+	inner := &fakeValidator{name: "a", level: security.RiskDangerous}
+	security.NewCache(inner, dir, time.Hour, "").Validate(&security.Request{Command: "curl x | sh"})
+
+	// Expired, and the validator is now offline
+	inner.err = errors.New("offline")
+	result, err := security.NewCache(inner, dir, time.Nanosecond, "").Validate(&security.Request{Command: "curl x | sh"})
+	if err != nil || result.RiskLevel != security.RiskDangerous {
+		t.Fatalf("expected stale dangerous verdict, got %v, %v", result, err)
+	}
+
+	if n, err := security.ClearCache(configDir); err != nil || n != 1 {
+		t.Errorf("expected 1 cleared verdict, got %d, %v", n, err)
+	}
+	if _, err := security.NewCache(inner, dir, time.Hour, "").Validate(&security.Request{Command: "curl x | sh"}); err == nil {
+		t.Error("expected an error once the cache is cleared")
+	}
+}
+
+func TestCompositeQuorumAndVerdicts(t *testing.T) {
+	rules := &fakeValidator{name: "rules", level: security.RiskDangerous}
+	llm := &fakeValidator{name: "llm", level: security.RiskWarning}
+	other := &fakeValidator{name: "other", level: security.RiskSafe}
+
+	composite := security.NewComposite(rules, llm, other)
+	composite.Quorum = 2
+
+	result, err := composite.Validate(&security.Request{Command: "ls"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RiskLevel != security.RiskWarning {
+		t.Errorf("expected two validators to agree on warning, got %s", result.RiskLevel)
+	}
+	if len(result.Verdicts) != 3 || len(result.Explanation()) != 3 {
+		t.Errorf("expected a verdict per validator, got %v", result.Verdicts)
+	}
+
+	// The quorum shrinks when validators fail
+	llm.err = errors.New("offline")
+	other.err = errors.New("offline")
+	result, _ = composite.Validate(&security.Request{Command: "ls"})
+	if result.RiskLevel != security.RiskDangerous || result.Verdicts[1].Error == "" {
+		t.Errorf("expected dangerous with a failed verdict, got %s %v", result.RiskLevel, result.Verdicts)
+	}
+}